@@ -1,21 +1,133 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"runtime"
+	"strings"
+	"syscall"
 	"time"
 	"webcrawler-go/internal/crawler"
 	"webcrawler-go/internal/dependencies"
 	"webcrawler-go/internal/fetcher"
+	"webcrawler-go/internal/graph"
+	"webcrawler-go/internal/linkcheck"
+	"webcrawler-go/internal/output"
+	"webcrawler-go/internal/ratelimit"
+	"webcrawler-go/internal/robots"
+	"webcrawler-go/internal/sitemap"
+	"webcrawler-go/internal/urlnorm"
 )
 
 func main() {
+	// Logs go to stderr so that -output can be piped into other tools.
+	log.SetOutput(os.Stderr)
+
+	// Deferred first so that it runs last, once everything else has been cleaned up.
+	exitCode := 0
+	defer func() {
+		if exitCode != 0 {
+			os.Exit(exitCode)
+		}
+	}()
+
 	cfg := dependencies.LoadEnv()
 	arg := flag.String("targetUrl", "", "the starting URL that the web-crawler should crawl from.")
+	resume := flag.String("resume", "", "the checkpoint that the web-crawler should resume crawling from.")
+	graphPath := flag.String("graph", "", "the file that the graph of links between crawled pages should be exported to.")
+	graphFormat := flag.String("graphFormat", graph.FormatDOT, "the format of the exported graph: dot, graphml or csv.")
+	outputFormat := flag.String("output", "", "write one record per page in this format once the crawl is done: ndjson, csv or json.")
+	outputPath := flag.String("outputFile", "", "the file that the -output records should be written to. Defaults to stdout.")
+	sitemapDir := flag.String("sitemap", "", "the directory that a sitemap.xml of the crawled pages should be written to.")
+	sitemapBaseUrl := flag.String("sitemapBaseUrl", "", "where the -sitemap directory will be served from. Defaults to the root of the starting URL.")
+	seedFromSitemaps := flag.Bool("sitemaps", false, "also crawl from every page listed in the site's sitemaps.")
+	var scopeHosts, scopePaths, scopeInclude, scopeExclude, scopeSchemes listFlag
+	flag.Var(&scopeHosts, "scopeHost", "only follow links to this host, e.g. *.monzo.com. Can be given more than once. Overrides SCOPE_HOSTS.")
+	flag.Var(&scopePaths, "scopePath", "only follow links whose path starts with this prefix. Can be given more than once. Overrides SCOPE_PATH_PREFIXES.")
+	flag.Var(&scopeInclude, "include", "only follow links that match this regex. Can be given more than once. Overrides SCOPE_INCLUDE.")
+	flag.Var(&scopeExclude, "exclude", "never follow links that match this regex. Can be given more than once. Overrides SCOPE_EXCLUDE.")
+	flag.Var(&scopeSchemes, "scheme", "only follow links with this scheme. Can be given more than once. Overrides SCOPE_SCHEMES.")
+	redirectsPath := flag.String("redirects", "", "the CSV file that long redirect chains and redirects out of scope should be written to.")
+	checkLinks := flag.Bool("check", false, "check that every link works, including links to other sites, and exit with status 1 if any are broken.")
+	coveragePath := flag.String("coverage", "", "the file that a CSV of the pages missing from either the -sitemaps or the crawl should be written to.")
 	flag.Parse()
 
-	if *arg == "" {
-		log.Fatal("web-crawler needs a starting URL")
+	if *arg == "" && *resume == "" {
+		log.Fatal("web-crawler needs a starting URL or a checkpoint to resume from")
+	}
+
+	scopeHosts.override(&cfg.ScopeHosts)
+	scopePaths.override(&cfg.ScopePathPrefixes)
+	scopeInclude.override(&cfg.ScopeInclude)
+	scopeExclude.override(&cfg.ScopeExclude)
+	scopeSchemes.override(&cfg.ScopeSchemes)
+
+	scope, err := crawler.NewScope(
+		crawler.WithHosts(cfg.ScopeHosts...),
+		crawler.WithPathPrefixes(cfg.ScopePathPrefixes...),
+		crawler.WithIncludePatterns(cfg.ScopeInclude...),
+		crawler.WithExcludePatterns(cfg.ScopeExclude...),
+		crawler.WithSchemes(cfg.ScopeSchemes...),
+	)
+	if err != nil {
+		log.Fatalf("error loading app config: %v", err)
+	}
+
+	if *seedFromSitemaps && *arg == "" {
+		log.Fatal("web-crawler needs a starting URL to discover sitemaps from")
+	}
+
+	switch *graphFormat {
+	case graph.FormatDOT, graph.FormatGraphML, graph.FormatCSV:
+	default:
+		log.Fatalf("unknown graph format %q - expected dot, graphml or csv", *graphFormat)
+	}
+
+	switch *outputFormat {
+	case "", output.FormatNDJSON, output.FormatCSV, output.FormatJSON:
+	default:
+		log.Fatalf("unknown output format %q - expected ndjson, csv or json", *outputFormat)
+	}
+
+	var checkpoint *crawler.Checkpoint
+	if *resume != "" {
+		var err error
+		if checkpoint, err = crawler.LoadCheckpoint(*resume); err != nil {
+			log.Fatalf("unable to resume crawl: %v", err)
+		}
+		if cfg.CheckpointPath == "" {
+			cfg.CheckpointPath = *resume
+		}
+	}
+
+	if cfg.CheckpointPath != "" && cfg.MaxCrawlConcurrencyLevel <= 0 {
+		log.Fatal("error loading app config: checkpoints need a bounded crawl - set MAX_CRAWL_CONCURRENCY_LEVEL to the no. of workers to use")
+	}
+
+	if cfg.CheckpointPath != "" && cfg.VisitedStore == crawler.VisitedStoreBloom {
+		log.Fatal("error loading app config: checkpoints can't be used with the bloom visited store")
+	}
+
+	if cfg.CheckpointPath != "" && cfg.VisitedStore == crawler.VisitedStoreFile {
+		log.Fatal("error loading app config: checkpoints can't be used with the file visited store")
+	}
+
+	// The bloom visited store is for crawls too large to fit in memory, which rules out the reports that need every page.
+	lowMemory := cfg.VisitedStore == crawler.VisitedStoreBloom
+	if lowMemory {
+		for _, f := range []struct {
+			name string
+			set  bool
+		}{{"-check", *checkLinks}, {"-graph", *graphPath != ""}, {"-sitemap", *sitemapDir != ""}, {"-sitemaps", *seedFromSitemaps}, {"-redirects", *redirectsPath != ""}} {
+			if f.set {
+				log.Fatalf("error loading app config: %s can't be used with the bloom visited store, as it needs every page in memory", f.name)
+			}
+		}
 	}
 
 	// 👋 Enable for benchmarking purposes
@@ -31,18 +143,485 @@ func main() {
 
 	start := time.Now()
 
-	f := fetcher.NewFetcher()
-	c := crawler.NewCrawler(cfg, f)
+	httpFetcher := fetcher.NewFetcher(fetcherOptions(cfg)...)
 
-	if cfg.MaxCrawlConcurrencyLevel > 0 {
-		log.Println("Running in BOUNDED mode...")
-		c.RunBounded(*arg, 1)
+	var r robots.IRobots
+	if cfg.RespectRobotsTxt {
+		r = robots.NewRobots(httpFetcher.Client(), cfg.UserAgent)
+	}
+
+	// Pages and checked links share the same limiter, so that they draw from the same per-host budget.
+	limiter := rateLimiter(cfg, r)
+	f := decorate(cfg, httpFetcher, limiter)
+
+	frontier, err := crawler.NewFrontier(cfg.CrawlStrategy, cfg.CrawlPriority, cfg.CrawlPriorityPatterns)
+	if err != nil {
+		log.Fatalf("error loading app config: %v", err)
+	}
+
+	visited, err := crawler.NewVisitedStore(cfg)
+	if err != nil {
+		log.Fatalf("error loading app config: %v", err)
+	}
+	if fileStore, ok := visited.(*crawler.FileVisitedStore); ok {
+		defer fileStore.Close()
+		// Links left behind by a previous run may have still been in flight when it stopped, so they can't be trusted.
+		if err := fileStore.Reset(); err != nil {
+			log.Fatalf("unable to reset visited store: %v", err)
+		}
+	}
+
+	norm, err := urlnorm.NewNormalizer(
+		urlnorm.WithStripParams(cfg.UrlStripParams...),
+		urlnorm.WithTrailingSlash(urlnorm.TrailingSlash(cfg.UrlTrailingSlash)),
+		urlnorm.WithSortQuery(cfg.UrlSortQuery),
+	)
+	if err != nil {
+		log.Fatalf("error loading app config: %v", err)
+	}
+
+	opts := []crawler.Option{crawler.WithFrontier(frontier), crawler.WithVisitedStore(visited), crawler.WithScope(scope), crawler.WithNormalizer(norm), crawler.WithRespectNoFollow(cfg.RespectNoFollow)}
+	if r != nil {
+		opts = append(opts, crawler.WithRobots(r))
+	}
+	if cfg.TrapMaxPathSegments > 0 || cfg.TrapMaxRepeatedSegments > 0 || cfg.TrapMaxQueryVariants > 0 || cfg.TrapMaxUrlsPerPattern > 0 {
+		opts = append(opts, crawler.WithTrapDetector(crawler.NewTrapDetector(
+			crawler.WithMaxPathSegments(cfg.TrapMaxPathSegments),
+			crawler.WithMaxRepeatedSegments(cfg.TrapMaxRepeatedSegments),
+			crawler.WithMaxQueryVariants(cfg.TrapMaxQueryVariants),
+			crawler.WithMaxUrlsPerPattern(cfg.TrapMaxUrlsPerPattern),
+		)))
+	}
+	followKinds, err := fetcher.ParseLinkKinds(cfg.FollowLinkKinds)
+	if err != nil {
+		log.Fatalf("invalid FOLLOW_LINK_KINDS: %v", err)
+	}
+	opts = append(opts, crawler.WithFollowKinds(followKinds...))
+
+	checker := decorate(cfg, fetcher.NewChecker(httpFetcher), limiter)
+	if *checkLinks {
+		opts = append(opts, crawler.WithLinkChecker(checker))
 	} else {
-		log.Println("Running in UNBOUNDED mode...")
-		c.RunUnbounded(*arg, 1)
+		opts = append(opts, crawler.WithAssetChecker(checker))
+	}
+	if cfg.CheckpointPath != "" {
+		opts = append(opts, crawler.WithCheckpoints(cfg.CheckpointPath, cfg.CheckpointInterval))
+	}
+
+	// The sitemap coverage report and the broken links report need to know which pages were linked to.
+	var g *graph.Graph
+	if *graphPath != "" || *seedFromSitemaps || *checkLinks {
+		g = graph.NewGraph()
+		opts = append(opts, crawler.WithGraph(g))
+	}
+
+	// In low memory mode, results are streamed out as they're recorded, or only counted, rather than kept until the crawl is done.
+	var (
+		stream      *output.StreamSink
+		closeOutput func() error
+	)
+	if lowMemory {
+		// A second filter stands in for the set of links that weren't crawled. It's a bloom store, so it can't fail.
+		outOfScope, _ := crawler.NewVisitedStore(cfg)
+		opts = append(opts, crawler.WithLowMemory(outOfScope))
+
+		if *outputFormat != "" {
+			if stream, closeOutput, err = streamOutput(*outputPath, *outputFormat); err != nil {
+				log.Fatalf("unable to write output - %v", err)
+			}
+			opts = append(opts, crawler.WithResultSink(stream))
+		} else {
+			opts = append(opts, crawler.WithResultSink(crawler.NewCountingSink()))
+		}
 	}
 
+	c := crawler.NewCrawler(cfg, f, opts...)
+	if checkpoint != nil {
+		c.Restore(checkpoint)
+		log.Printf("resuming crawl from %s - %d links visited, %d pending\n", *resume, len(checkpoint.Visited), len(checkpoint.Frontier))
+	}
+
+	// Ctrl-C stops the crawl gracefully; a second one kills the process as usual.
+	sigCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-sigCtx.Done()
+		stop()
+	}()
+
+	ctx := sigCtx
+	if cfg.MaxCrawlDuration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.MaxCrawlDuration)
+		defer cancel()
+	}
+
+	var seeds []string
+	if *arg != "" {
+		seeds = append(seeds, *arg)
+	}
+
+	var listed []sitemap.URL
+	if *seedFromSitemaps {
+		listed = discoverSitemaps(ctx, cfg, httpFetcher.Client(), r, *arg)
+
+		// The listed pages are compared with the crawled ones, which are normalized.
+		for i := range listed {
+			if n, err := norm.Normalize(listed[i].Loc); err == nil {
+				listed[i].Loc = n
+			}
+		}
+
+		for _, u := range listed {
+			if _, ok := scope.Check(*arg, u.Loc); ok {
+				seeds = append(seeds, u.Loc)
+			}
+		}
+		log.Printf("found %d pages in sitemaps, %d of them in scope\n", len(listed), len(seeds)-1)
+	}
+
+	c.Run(ctx, seeds...)
+
 	end := time.Now()
 
-	log.Printf("✅ web-crawler visited %d links and took %v to complete.\n", len(c.Visited), end.Sub(start))
+	if err := ctx.Err(); err != nil {
+		log.Printf("⚠️ web-crawler stopped early - %v\n", err)
+	}
+
+	// Every report below is worked out from the results, which are only kept around when they're collected in memory.
+	var results []*crawler.PageResult
+	if sink, ok := c.Results().(*crawler.MemorySink); ok {
+		results = sink.Results()
+	}
+
+	if stream != nil {
+		summary := output.NewSummaryFromCounts(start, end.Sub(start), stream.Counts(), ctx.Err())
+		if err := stream.Close(summary); err != nil {
+			log.Printf("unable to write output - %v\n", err)
+		}
+		if err := closeOutput(); err != nil {
+			log.Printf("unable to write output - %v\n", err)
+		}
+	} else if *outputFormat != "" {
+		summary := output.NewSummary(start, end.Sub(start), results, ctx.Err())
+		if err := writeOutput(*outputPath, *outputFormat, summary, results); err != nil {
+			log.Printf("unable to write output - %v\n", err)
+		}
+	}
+
+	if *sitemapDir != "" {
+		if err := writeSitemap(*sitemapDir, *sitemapBaseUrl, results); err != nil {
+			log.Printf("unable to write sitemap - %v\n", err)
+		}
+	}
+
+	if *seedFromSitemaps {
+		siteUrl, err := norm.Normalize(*arg)
+		if err != nil {
+			siteUrl = *arg
+		}
+		if err := reportCoverage(*coveragePath, siteUrl, listed, g, results); err != nil {
+			log.Printf("unable to write sitemap coverage - %v\n", err)
+		}
+	}
+
+	if chains := linkcheck.FindRedirects(results, scope, cfg.HttpLongRedirectChain); len(chains) > 0 || *redirectsPath != "" {
+		log.Printf("found %d long redirect chains or redirects out of scope.\n", len(chains))
+		if *redirectsPath != "" {
+			if err := writeRedirects(*redirectsPath, chains); err != nil {
+				log.Printf("unable to write redirects - %v\n", err)
+			}
+		}
+	}
+
+	if quarantined := countStatus(c.Results(), results, crawler.PageQuarantined); quarantined > 0 {
+		log.Printf("⚠️ quarantined %d links that looked like crawler traps.\n", quarantined)
+	}
+	if noFollow := countStatus(c.Results(), results, crawler.PageNoFollow); noFollow > 0 {
+		log.Printf("skipped %d links that were only linked to with nofollow.\n", noFollow)
+	}
+
+	if *checkLinks {
+		broken := linkcheck.Find(results, g.Edges())
+
+		// The report goes to stdout, unless -output is already using it.
+		w := os.Stdout
+		if *outputFormat != "" && *outputPath == "" {
+			w = os.Stderr
+		}
+		if err := linkcheck.Write(w, broken); err != nil {
+			log.Printf("unable to write broken links - %v\n", err)
+		}
+
+		if len(broken) > 0 {
+			fragments := 0
+			for _, b := range broken {
+				if b.Kind == linkcheck.KindFragment {
+					fragments++
+				}
+			}
+			log.Printf("❌ found %d broken links and %d links to missing fragments.\n", len(broken)-fragments, fragments)
+			exitCode = 1
+		} else {
+			log.Println("no broken links found.")
+		}
+	}
+
+	if *graphPath != "" {
+		if err := writeGraph(g, *graphPath, *graphFormat); err != nil {
+			log.Printf("unable to export graph to %s - %v\n", *graphPath, err)
+		} else {
+			log.Printf("exported %d links between %d pages to %s.\n", len(g.Edges()), len(g.Nodes()), *graphPath)
+		}
+	}
+
+	// Sys is all the memory the runtime took from the OS, so it covers the crawl's peak rather than what's left at the end.
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	if r, ok := c.Visited().(crawler.MemoryReporter); ok {
+		log.Printf("web-crawler used ~%.1f MB of memory, ~%.1f MB of it for the visited store.\n", float64(mem.Sys)/(1<<20), float64(r.MemoryUsage())/(1<<20))
+	} else {
+		log.Printf("web-crawler used ~%.1f MB of memory.\n", float64(mem.Sys)/(1<<20))
+	}
+
+	log.Printf("✅ web-crawler visited %d links (%d skipped) and took %v to complete.\n", c.Visited().Len(), c.SkippedLen(), end.Sub(start))
+}
+
+// writeRedirects writes the redirect chains to a CSV file at path.
+func writeRedirects(path string, chains []linkcheck.RedirectChain) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := linkcheck.WriteRedirectsCSV(f, chains); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// statusCounter is implemented by result sinks that count results by status without keeping them, e.g. in low memory mode.
+type statusCounter interface {
+	Count(status crawler.PageStatus) int
+}
+
+// countStatus counts the results with the given status, asking the sink when it keeps count itself.
+func countStatus(sink crawler.ResultSink, results []*crawler.PageResult, status crawler.PageStatus) int {
+	if counter, ok := sink.(statusCounter); ok {
+		return counter.Count(status)
+	}
+
+	n := 0
+	for _, r := range results {
+		if r.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// decorate wraps f so that it's rate limited and retries transient failures, as configured.
+func decorate(cfg *dependencies.Config, f fetcher.IFetcher, limiter ratelimit.ILimiter) fetcher.IFetcher {
+	if limiter != nil {
+		f = fetcher.NewRateLimitedFetcher(f, limiter)
+	}
+	if cfg.RetryMaxAttempts > 1 {
+		f = fetcher.NewRetryFetcher(f,
+			fetcher.WithMaxAttempts(cfg.RetryMaxAttempts),
+			fetcher.WithBaseDelay(cfg.RetryBaseDelay),
+			fetcher.WithMaxDelay(cfg.RetryMaxDelay),
+			fetcher.WithJitter(cfg.RetryJitter),
+		)
+	}
+	return f
+}
+
+// listFlag collects the values of a flag that can be given more than once.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// override replaces the config value with the flag's values, if the flag was given at all.
+func (l listFlag) override(v *[]string) {
+	if len(l) > 0 {
+		*v = l
+	}
+}
+
+// writeOutput writes the crawl's records to path, or to stdout if there's no path. Logs go to stderr, so they never mix.
+func writeOutput(path, format string, summary output.Summary, results []*crawler.PageResult) error {
+	if path == "" {
+		return output.Write(os.Stdout, format, summary, results)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := output.Write(f, format, summary, results); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// streamOutput opens the -output file, or stdout when there's none, for results to be written to as they're recorded.
+// The returned func closes the file.
+func streamOutput(path, format string) (*output.StreamSink, func() error, error) {
+	if path == "" {
+		s, err := output.NewStreamSink(os.Stdout, format)
+		return s, func() error { return nil }, err
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, err := output.NewStreamSink(f, format)
+	if err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+
+	return s, f.Close, nil
+}
+
+// writeSitemap writes the crawled pages that belong in a sitemap into dir. Unless baseUrl is set, the sitemap is expected to be
+// served from the root of the site that the crawl started from.
+func writeSitemap(dir, baseUrl string, results []*crawler.PageResult) error {
+	if baseUrl == "" {
+		for _, r := range results {
+			if u, err := url.Parse(r.URL); err == nil && r.Parent == "" {
+				baseUrl = u.Scheme + "://" + u.Host + "/"
+				break
+			}
+		}
+	}
+
+	urls := sitemap.FromResults(results)
+	paths, err := sitemap.NewGenerator().Write(dir, baseUrl, urls)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("wrote %d pages to %s.\n", len(urls), strings.Join(paths, ", "))
+	return nil
+}
+
+// discoverSitemaps reads the sitemaps listed in the site's robots.txt, whether or not robots.txt is respected, as well as its
+// /sitemap.xml.
+func discoverSitemaps(ctx context.Context, cfg *dependencies.Config, client *http.Client, r robots.IRobots, siteUrl string) []sitemap.URL {
+	rr, ok := r.(*robots.Robots)
+	if !ok {
+		rr = robots.NewRobots(client, cfg.UserAgent)
+	}
+
+	urls, err := sitemap.NewReader(client, cfg.UserAgent).Discover(ctx, siteUrl, rr.Sitemaps(ctx, siteUrl))
+	if err != nil {
+		log.Printf("unable to discover sitemaps - %v\n", err)
+	}
+
+	return urls
+}
+
+// reportCoverage compares the pages listed in the sitemaps with the ones that the crawl reached by following links from the
+// starting URL, and writes the differences to path if it's set.
+func reportCoverage(path, siteUrl string, listed []sitemap.URL, g *graph.Graph, results []*crawler.PageResult) error {
+	linked := []string{siteUrl}
+	for _, e := range g.Edges() {
+		if e.Source != e.Target {
+			linked = append(linked, e.Target)
+		}
+	}
+
+	coverage := sitemap.NewCoverage(listed, linked, sitemap.FromResults(results))
+	log.Printf("%d pages in sitemaps weren't linked to, and %d crawled pages weren't in sitemaps.\n", len(coverage.SitemapOnly), len(coverage.CrawlOnly))
+
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := coverage.WriteCSV(f); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func writeGraph(g *graph.Graph, path, format string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	if err := g.Write(f, format); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func fetcherOptions(cfg *dependencies.Config) []fetcher.Option {
+	opts := []fetcher.Option{
+		fetcher.WithTimeout(cfg.HttpTimeout),
+		fetcher.WithResponseHeaderTimeout(cfg.HttpResponseHeaderTimeout),
+		fetcher.WithTLSHandshakeTimeout(cfg.HttpTlsHandshakeTimeout),
+		fetcher.WithUserAgent(cfg.UserAgent),
+		fetcher.WithHeaders(cfg.HttpHeaders),
+		fetcher.WithMaxIdleConnsPerHost(cfg.HttpMaxIdleConnsPerHost),
+		fetcher.WithMaxRedirects(cfg.HttpMaxRedirects),
+		fetcher.WithMaxBodySize(cfg.HttpMaxBodySize),
+	}
+
+	linkKinds, err := fetcher.ParseLinkKinds(cfg.LinkKinds)
+	if err != nil {
+		log.Fatalf("invalid LINK_KINDS: %v", err)
+	}
+	opts = append(opts, fetcher.WithLinkKinds(linkKinds...))
+
+	if cfg.HttpProxyUrl != "" {
+		proxyUrl, err := url.Parse(cfg.HttpProxyUrl)
+		if err != nil {
+			log.Fatalf("invalid HTTP_PROXY_URL: %v", err)
+		}
+		opts = append(opts, fetcher.WithProxy(proxyUrl))
+	}
+
+	return opts
+}
+
+// rateLimiter builds the per-host limiter shared by all crawl workers, or returns nil if nothing needs limiting.
+func rateLimiter(cfg *dependencies.Config, r robots.IRobots) ratelimit.ILimiter {
+	if cfg.RateLimitRps <= 0 && cfg.RateLimitMinDelay <= 0 && r == nil {
+		return nil
+	}
+
+	opts := []ratelimit.Option{
+		ratelimit.WithBurst(cfg.RateLimitBurst),
+		ratelimit.WithMinDelay(cfg.RateLimitMinDelay),
+	}
+	if r != nil {
+		opts = append(opts, ratelimit.WithDelayFunc(r.CrawlDelay))
+	}
+
+	return ratelimit.NewLimiter(cfg.RateLimitRps, opts...)
 }
@@ -0,0 +1,177 @@
+package crawler
+
+import (
+	"hash/fnv"
+	"math"
+	"sync"
+)
+
+// BloomVisitedStore is a scalable Bloom filter (Almeida et al., 2007) for crawls too large to hold every link in memory.
+// It only needs a couple of bytes per link, but it's probabilistic: a link that was never visited may be reported as
+// visited (and therefore never crawled) at roughly the configured false-positive rate. It can't list the links it holds,
+// so Iterate never calls fn.
+//
+// The store starts with a single filter sized for the expected no. of links. Once that fills up, a filter twice the size with
+// a tighter error rate is added, which keeps the overall false-positive rate within bounds however large the crawl grows.
+type BloomVisitedStore struct {
+	filters   []*bloomFilter
+	capacity  int     // the no. of links the next filter is sized for
+	errorRate float64 // the false-positive rate of the next filter
+	count     int
+	lock      sync.RWMutex
+}
+
+const (
+	bloomGrowth     = 2   // each new filter holds this many times more links than the last
+	bloomTightening = 0.5 // each new filter's error rate is this fraction of the last one's
+)
+
+// NewBloomVisitedStore builds a store expecting roughly expectedUrls links with the given overall false-positive rate (e.g. 0.001).
+func NewBloomVisitedStore(expectedUrls int, falsePositiveRate float64) *BloomVisitedStore {
+	if expectedUrls < 1 {
+		expectedUrls = 1
+	}
+	if falsePositiveRate <= 0 || falsePositiveRate >= 1 {
+		falsePositiveRate = 0.001
+	}
+
+	s := &BloomVisitedStore{
+		capacity: expectedUrls,
+		// The series' error rates add up to at most P0 / (1 - r), so the first one has to make room for the rest.
+		errorRate: falsePositiveRate * (1 - bloomTightening),
+	}
+	s.grow()
+
+	return s
+}
+
+func (s *BloomVisitedStore) grow() {
+	s.filters = append(s.filters, newBloomFilter(s.capacity, s.errorRate))
+	s.capacity *= bloomGrowth
+	s.errorRate *= bloomTightening
+}
+
+func (s *BloomVisitedStore) MarkIfNew(url string) bool {
+	h1, h2 := bloomHashes(url)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.has(h1, h2) {
+		return false
+	}
+
+	f := s.filters[len(s.filters)-1]
+	if f.count >= f.capacity {
+		s.grow()
+		f = s.filters[len(s.filters)-1]
+	}
+	f.add(h1, h2)
+	s.count++
+
+	return true
+}
+
+func (s *BloomVisitedStore) Has(url string) bool {
+	h1, h2 := bloomHashes(url)
+
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.has(h1, h2)
+}
+
+func (s *BloomVisitedStore) has(h1, h2 uint64) bool {
+	for _, f := range s.filters {
+		if f.has(h1, h2) {
+			return true
+		}
+	}
+	return false
+}
+
+// Len returns the no. of links marked as new, which undercounts the links offered to the store by the no. of false positives.
+func (s *BloomVisitedStore) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.count
+}
+
+func (s *BloomVisitedStore) Iterate(fn func(url string) bool) {}
+
+func (s *BloomVisitedStore) MemoryUsage() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	var n uint64
+	for _, f := range s.filters {
+		n += uint64(len(f.bits)) * 8
+	}
+	return n
+}
+
+type bloomFilter struct {
+	bits     []uint64
+	m        uint64 // no. of bits
+	k        uint64 // no. of hash functions
+	capacity int
+	count    int
+}
+
+// newBloomFilter sizes a filter to hold n items at false-positive rate p, using the optimal m = -n·ln(p)/ln(2)² bits
+// and k = log2(1/p) hash functions.
+func newBloomFilter(n int, p float64) *bloomFilter {
+	m := uint64(math.Ceil(-float64(n) * math.Log(p) / (math.Ln2 * math.Ln2)))
+	if m < 64 {
+		m = 64
+	}
+	k := uint64(math.Ceil(-math.Log2(p)))
+	if k < 1 {
+		k = 1
+	}
+
+	return &bloomFilter{
+		bits:     make([]uint64, (m+63)/64),
+		m:        m,
+		k:        k,
+		capacity: n,
+	}
+}
+
+func (f *bloomFilter) add(h1, h2 uint64) {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		f.bits[bit/64] |= 1 << (bit % 64)
+	}
+	f.count++
+}
+
+func (f *bloomFilter) has(h1, h2 uint64) bool {
+	for i := uint64(0); i < f.k; i++ {
+		bit := (h1 + i*h2) % f.m
+		if f.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bloomHashes derives the two hashes that the k probes are built from (Kirsch & Mitzenmacher, 2006).
+// The second one is a mix of the first rather than a separate pass over the string.
+func bloomHashes(s string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	h1 := h.Sum64()
+
+	// splitmix64's finaliser.
+	h2 := h1
+	h2 ^= h2 >> 30
+	h2 *= 0xbf58476d1ce4e5b9
+	h2 ^= h2 >> 27
+	h2 *= 0x94d049bb133111eb
+	h2 ^= h2 >> 31
+
+	// A zero step would make all k probes land on the same bit.
+	return h1, h2 | 1
+}
@@ -0,0 +1,130 @@
+package crawler
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestBloomVisitedStore(t *testing.T) {
+	t.Run("never forgets a visited link", func(t *testing.T) {
+		s := NewBloomVisitedStore(1000, 0.01)
+
+		for i := 0; i < 5000; i++ {
+			s.MarkIfNew(fmt.Sprintf("https://monzo.com/%d/", i))
+		}
+
+		for i := 0; i < 5000; i++ {
+			u := fmt.Sprintf("https://monzo.com/%d/", i)
+			assert.True(t, s.Has(u), u)
+			assert.False(t, s.MarkIfNew(u), u)
+		}
+	})
+
+	t.Run("stays within the false-positive rate as it grows", func(t *testing.T) {
+		const rate = 0.01
+		s := NewBloomVisitedStore(1000, rate)
+
+		// Ten times more links than expected forces the store to add a few filters.
+		falseNegatives := 0
+		for i := 0; i < 10_000; i++ {
+			if !s.MarkIfNew(fmt.Sprintf("https://monzo.com/visited/%d/", i)) {
+				falseNegatives++
+			}
+		}
+		assert.Greater(t, len(s.filters), 1)
+
+		falsePositives := 0
+		for i := 0; i < 100_000; i++ {
+			if s.Has(fmt.Sprintf("https://monzo.com/unvisited/%d/", i)) {
+				falsePositives++
+			}
+		}
+
+		assert.LessOrEqual(t, float64(falsePositives)/100_000, rate)
+		assert.Equal(t, 10_000-falseNegatives, s.Len(), "links wrongly reported as visited aren't counted")
+	})
+
+	t.Run("uses far less memory than a map", func(t *testing.T) {
+		bloom := NewBloomVisitedStore(10_000, 0.001)
+		memory := NewMemoryVisitedStore()
+		for i := 0; i < 10_000; i++ {
+			u := fmt.Sprintf("https://monzo.com/blog/2023/01/01/some-article-%d/", i)
+			bloom.MarkIfNew(u)
+			memory.MarkIfNew(u)
+		}
+
+		// ~14.4 bits per link at 0.1%.
+		assert.Less(t, bloom.MemoryUsage(), uint64(20_000))
+		assert.Less(t, bloom.MemoryUsage()*10, memory.MemoryUsage())
+	})
+
+	t.Run("is safe for concurrent use", func(t *testing.T) {
+		s := NewBloomVisitedStore(100, 0.001)
+
+		var (
+			wg     sync.WaitGroup
+			marked atomic.Int64
+		)
+		for w := 0; w < 8; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 500; i++ {
+					if s.MarkIfNew(fmt.Sprintf("https://monzo.com/%d/", i)) {
+						marked.Add(1)
+					}
+				}
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, int64(s.Len()), marked.Load())
+		assert.LessOrEqual(t, s.Len(), 500)
+	})
+}
+
+// BenchmarkVisitedStore compares the throughput of marking new links in each store, along with the heap each store
+// grows by per link. Run with: go test -bench VisitedStore -benchmem ./internal/crawler/
+func BenchmarkVisitedStore(b *testing.B) {
+	stores := map[string]func(n int) VisitedStore{
+		"memory":  func(int) VisitedStore { return NewMemoryVisitedStore() },
+		"sharded": func(int) VisitedStore { return NewShardedVisitedStore(64) },
+		"bloom":   func(n int) VisitedStore { return NewBloomVisitedStore(n, 0.001) },
+	}
+
+	for _, name := range []string{"memory", "sharded", "bloom"} {
+		newStore := stores[name]
+
+		b.Run(name, func(b *testing.B) {
+			urls := make([]string, b.N)
+			for i := range urls {
+				urls[i] = fmt.Sprintf("https://monzo.com/blog/2023/01/01/some-article-%d/", i)
+			}
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			s := newStore(b.N)
+
+			var next atomic.Int64
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					s.MarkIfNew(urls[next.Add(1)-1])
+				}
+			})
+			b.StopTimer()
+
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/float64(b.N), "heap-bytes/url")
+			runtime.KeepAlive(s)
+			runtime.KeepAlive(urls)
+		})
+	}
+}
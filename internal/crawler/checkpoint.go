@@ -0,0 +1,166 @@
+package crawler
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+	"webcrawler-go/internal/graph"
+)
+
+const checkpointVersion = 1
+
+// Checkpoint is a snapshot of a bounded crawl's progress, from which the crawl can be resumed after a crash or an interruption.
+type Checkpoint struct {
+	Version  int               `json:"version"`
+	SavedAt  time.Time         `json:"savedAt"`
+	Frontier []*FrontierItem   `json:"frontier"` // Links still to be crawled, including the ones that were in flight.
+	Visited  []string          `json:"visited"`  // Links that were fully dealt with.
+	Skipped  map[string]string `json:"skipped"`
+	Results  []*PageResult     `json:"results,omitempty"`  // Only kept when results are recorded in memory.
+	Edges    []graph.Edge      `json:"edges,omitempty"`    // Only kept when the link graph is recorded in memory.
+	Fetched  int64             `json:"fetched"`            // The no. of pages fetched so far.
+	NoFollow []*FrontierItem   `json:"noFollow,omitempty"` // Links that were only found with rel="nofollow" or on nofollow pages so far.
+}
+
+// Save writes the checkpoint to path. It's written to a temporary file first, so that a crash halfway through never leaves a
+// corrupt checkpoint behind.
+func (cp *Checkpoint) Save(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := json.NewEncoder(tmp).Encode(cp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	cp := &Checkpoint{}
+	if err := json.NewDecoder(f).Decode(cp); err != nil {
+		return nil, fmt.Errorf("unable to read checkpoint %s: %w", path, err)
+	}
+
+	if cp.Version != checkpointVersion {
+		return nil, fmt.Errorf("unsupported checkpoint version %d in %s", cp.Version, path)
+	}
+
+	return cp, nil
+}
+
+// checkpoint takes a snapshot of the crawl. Unfinished links are the ones that were taken off the frontier but not dealt with
+// yet; they go back into the frontier and are left out of the visited links, so that a resumed crawl picks them up again.
+// It must only be called from RunBounded's main worker.
+func (c *Crawler) checkpoint(unfinished []*FrontierItem) *Checkpoint {
+	pending := make(map[string]bool, len(unfinished))
+	for _, item := range unfinished {
+		pending[item.URL] = true
+	}
+
+	cp := &Checkpoint{
+		Version:  checkpointVersion,
+		SavedAt:  time.Now(),
+		Frontier: append(unfinished, c.frontier.Items()...),
+		Visited:  make([]string, 0, c.visited.Len()),
+		Skipped:  make(map[string]string),
+		Fetched:  c.fetched.Load(),
+	}
+
+	c.visited.Iterate(func(url string) bool {
+		if !pending[url] {
+			cp.Visited = append(cp.Visited, url)
+		}
+		return true
+	})
+
+	c.lock.Lock()
+	for url, reason := range c.Skipped {
+		if !pending[url] {
+			cp.Skipped[url] = reason
+		}
+	}
+	c.lock.Unlock()
+
+	if sink, ok := c.results.(*MemorySink); ok {
+		for _, r := range sink.Results() {
+			if !pending[r.URL] {
+				cp.Results = append(cp.Results, r)
+			}
+		}
+	}
+
+	if g, ok := c.graph.(*graph.Graph); ok {
+		cp.Edges = g.Edges()
+	}
+
+	c.lock.Lock()
+	for _, item := range c.noFollow {
+		cp.NoFollow = append(cp.NoFollow, item)
+	}
+	c.lock.Unlock()
+
+	return cp
+}
+
+// Restore loads a checkpoint into the crawler, so that the next Run carries on from where the checkpointed crawl left off.
+func (c *Crawler) Restore(cp *Checkpoint) {
+	for _, url := range cp.Visited {
+		c.visited.MarkIfNew(url)
+	}
+
+	c.lock.Lock()
+	for url, reason := range cp.Skipped {
+		c.Skipped[url] = reason
+	}
+	c.lock.Unlock()
+
+	// The trap detector's limits carry on from where they were, counting every link that wasn't quarantined.
+	if c.traps != nil {
+		for _, url := range cp.Visited {
+			if !strings.HasPrefix(cp.Skipped[url], reasonTrap) {
+				c.traps.Check(url)
+			}
+		}
+	}
+
+	for _, r := range cp.Results {
+		if r.Status == PageOutOfScope {
+			c.outOfScope.MarkIfNew(r.URL)
+		}
+		c.results.Record(r)
+	}
+
+	if c.graph != nil {
+		for _, e := range cp.Edges {
+			c.graph.AddEdge(e)
+		}
+	}
+
+	for _, item := range cp.Frontier {
+		c.frontier.Push(item)
+	}
+
+	c.lock.Lock()
+	for _, item := range cp.NoFollow {
+		c.noFollow[item.URL] = item
+	}
+	c.lock.Unlock()
+
+	c.fetched.Store(cp.Fetched)
+}
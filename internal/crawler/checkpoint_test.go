@@ -0,0 +1,152 @@
+package crawler
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"webcrawler-go/internal/dependencies"
+	"webcrawler-go/internal/fetcher"
+	"webcrawler-go/internal/graph"
+)
+
+// killingFetcher counts the pages it fetches and cancels the crawl once it has fetched limit of them, as if the process was killed.
+type killingFetcher struct {
+	fetcher fetcher.IFetcher
+	limit   int
+	cancel  context.CancelFunc
+	lock    sync.Mutex
+	fetched map[string]int
+}
+
+func (f *killingFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	f.lock.Lock()
+	if f.limit > 0 && len(f.fetched) >= f.limit {
+		f.lock.Unlock()
+		f.cancel()
+		return nil, ctx.Err()
+	}
+	f.fetched[targetUrl]++
+	f.lock.Unlock()
+
+	return f.fetcher.Fetch(ctx, targetUrl)
+}
+
+func TestCheckpoint(t *testing.T) {
+	os.Setenv("APP_ENV", "test")
+	cfg := dependencies.LoadEnv()
+	cfg.MaxCrawlConcurrencyLevel = 1
+
+	t.Run("saves and loads checkpoints", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoint.json")
+		cp := &Checkpoint{
+			Version:  checkpointVersion,
+			SavedAt:  time.Now().Truncate(time.Second),
+			Frontier: []*FrontierItem{{URL: "https://monzo.com/help/", Depth: 2}},
+			Visited:  []string{"https://monzo.com/"},
+			Skipped:  map[string]string{"https://monzo.com/switch/": reasonBlockedByRobots},
+			Fetched:  1,
+			NoFollow: []*FrontierItem{{URL: "https://monzo.com/ads/", Depth: 2, Parent: "https://monzo.com/"}},
+		}
+		require.NoError(t, cp.Save(path))
+
+		loaded, err := LoadCheckpoint(path)
+		require.NoError(t, err)
+		assert.True(t, cp.SavedAt.Equal(loaded.SavedAt))
+		loaded.SavedAt = cp.SavedAt
+		assert.Equal(t, cp, loaded)
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		assert.Len(t, entries, 1, "temporary files should be cleaned up")
+	})
+
+	t.Run("leaves out unknown last modified times", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoint.json")
+		lastModified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+		cp := &Checkpoint{
+			Version: checkpointVersion,
+			Results: []*PageResult{
+				{URL: "https://monzo.com/", Status: PageSucceeded, LastModified: &lastModified},
+				{URL: "https://monzo.com/help/", Status: PageSucceeded},
+			},
+		}
+		require.NoError(t, cp.Save(path))
+
+		data, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, 1, strings.Count(string(data), `"lastModified"`))
+
+		loaded, err := LoadCheckpoint(path)
+		require.NoError(t, err)
+		require.NotNil(t, loaded.Results[0].LastModified)
+		assert.True(t, lastModified.Equal(*loaded.Results[0].LastModified))
+		assert.Nil(t, loaded.Results[1].LastModified)
+	})
+
+	t.Run("rejects invalid checkpoints", func(t *testing.T) {
+		dir := t.TempDir()
+
+		_, err := LoadCheckpoint(filepath.Join(dir, "missing.json"))
+		assert.Error(t, err)
+
+		path := filepath.Join(dir, "checkpoint.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"version": 99}`), 0o644))
+		_, err = LoadCheckpoint(path)
+		assert.ErrorContains(t, err, "unsupported checkpoint version")
+	})
+
+	t.Run("resumes a killed crawl without fetching pages twice", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		first := &killingFetcher{fetcher: fetcher.NewMockFetcher(), limit: 2, cancel: cancel, fetched: make(map[string]int)}
+		c := NewCrawler(cfg, first, WithCheckpoints(path, time.Hour), WithGraph(graph.NewGraph()))
+		c.Run(ctx, "https://monzo.com/")
+		require.Error(t, ctx.Err())
+
+		cp, err := LoadCheckpoint(path)
+		require.NoError(t, err)
+		assert.EqualValues(t, 2, cp.Fetched)
+		assert.NotEmpty(t, cp.Frontier)
+		for url, reason := range cp.Skipped {
+			assert.NotEqual(t, reasonCrawlStopped, reason, "%s shouldn't be skipped for good because the crawl stopped", url)
+		}
+
+		second := &killingFetcher{fetcher: fetcher.NewMockFetcher(), fetched: make(map[string]int)}
+		g := graph.NewGraph()
+		resumed := NewCrawler(cfg, second, WithCheckpoints(path, time.Hour), WithGraph(g))
+		resumed.Restore(cp)
+		resumed.Run(context.Background(), "https://monzo.com/")
+
+		assert.ElementsMatch(t, []string{
+			"https://monzo.com/",
+			"https://monzo.com/current-account/",
+			"https://monzo.com/current-account/joint-account/",
+			"https://monzo.com/switch/",
+			"https://monzo.com/monzo-plus/",
+			"https://monzo.com/help/",
+		}, visitedUrls(resumed))
+
+		for url := range second.fetched {
+			assert.NotContains(t, first.fetched, url, "%s was fetched twice", url)
+			assert.Equal(t, 1, second.fetched[url])
+		}
+		assert.Len(t, second.fetched, 4)
+		assert.Len(t, resumed.Results().(*MemorySink).Results(), 7, "results from before the checkpoint should be kept")
+		assert.Len(t, g.Edges(), 11, "edges from before the checkpoint should be kept")
+		assert.EqualValues(t, 5, resumed.Fetched(), "every page but /help/ should be fetched once")
+
+		final, err := LoadCheckpoint(path)
+		require.NoError(t, err)
+		assert.Empty(t, final.Frontier)
+		assert.Len(t, final.Visited, 6)
+	})
+}
@@ -2,176 +2,854 @@ package crawler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"sort"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
 	"webcrawler-go/internal/dependencies"
 	"webcrawler-go/internal/fetcher"
+	"webcrawler-go/internal/graph"
+	"webcrawler-go/internal/robots"
+	"webcrawler-go/internal/urlnorm"
 )
 
+const (
+	reasonBlockedByRobots = "blocked by robots"
+	reasonCrawlStopped    = "crawl stopped"
+	reasonMaxDepth        = "max crawl depth reached"
+	reasonNoFollow        = "only linked to with nofollow"
+
+	reasonRedirectOutOfScope = "redirected out of scope - "
+)
+
+// defaultFollowKinds are the kinds of links that are crawled unless WithFollowKinds says otherwise. It's never modified.
+var defaultFollowKinds = map[fetcher.LinkKind]bool{fetcher.KindNavigation: true}
+
 type Crawler struct {
-	cfg     *dependencies.Config
-	fetcher fetcher.IFetcher
-	Visited map[string]bool
-	lock    sync.Mutex
+	cfg      *dependencies.Config
+	fetcher  fetcher.IFetcher
+	robots   robots.IRobots
+	scope    *Scope
+	norm     *urlnorm.Normalizer
+	traps    *TrapDetector
+	checker  fetcher.IFetcher
+	follow   map[fetcher.LinkKind]bool
+	graph    graph.IGraph
+	frontier Frontier
+	visited  VisitedStore
+	Skipped  map[string]string // Visited links that weren't crawled, along with the reason why. Empty in low memory mode.
+	skipped  atomic.Int64      // The no. of links in Skipped, which is only counted in low memory mode.
+	results  ResultSink
+	fetched  atomic.Int64
+
+	checkOutOfScope bool         // Whether links outside the crawl's scope are checked rather than only recorded.
+	outOfScope      VisitedStore // Links to other sites that were already recorded, as well as nofollow links in low memory mode.
+	lowMemory       bool
+	respectNoFollow bool
+	noFollow        map[string]*FrontierItem // Links that weren't followed because they asked not to be, by URL. Guarded by lock.
+	lock            sync.Mutex
+
+	checkpointPath     string
+	checkpointInterval time.Duration
+}
+
+type Option func(c *Crawler)
+
+// WithFrontier sets the order in which RunBounded crawls pending links. By default, links are crawled breadth-first.
+func WithFrontier(f Frontier) Option {
+	return func(c *Crawler) {
+		c.frontier = f
+	}
+}
+
+// WithVisitedStore sets where the crawler keeps track of visited links. By default, they're kept in memory.
+func WithVisitedStore(s VisitedStore) Option {
+	return func(c *Crawler) {
+		c.visited = s
+	}
+}
+
+// WithScope sets which links the crawler follows. By default, it only follows links to the same site.
+func WithScope(s *Scope) Option {
+	return func(c *Crawler) {
+		c.scope = s
+	}
+}
+
+// WithNormalizer sets how URLs are normalized before they're checked against the visited links. By default, only their
+// scheme, host, port, path, fragment and query order are normalized.
+func WithNormalizer(n *urlnorm.Normalizer) Option {
+	return func(c *Crawler) {
+		c.norm = n
+	}
+}
+
+// WithTrapDetector makes the crawler quarantine links that look like part of a crawler trap instead of crawling them.
+func WithTrapDetector(d *TrapDetector) Option {
+	return func(c *Crawler) {
+		c.traps = d
+	}
+}
+
+// WithLinkChecker makes the crawler check that every link outside its scope works with f, rather than only recording it.
+// Those links are never crawled any further, so f should avoid downloading them, e.g. fetcher.Checker. Links of kinds that
+// aren't followed are checked with f as well.
+func WithLinkChecker(f fetcher.IFetcher) Option {
+	return func(c *Crawler) {
+		c.checker = f
+		c.checkOutOfScope = true
+	}
+}
+
+// WithAssetChecker makes the crawler check links of kinds that aren't followed with f, e.g. fetcher.Checker, without
+// checking links outside its scope. By default, they're checked with the crawler's fetcher.
+func WithAssetChecker(f fetcher.IFetcher) Option {
+	return func(c *Crawler) {
+		c.checker = f
+	}
+}
+
+// WithFollowKinds sets which kinds of links the crawler crawls. Links of any other kind, e.g. images, are only checked to
+// work, without looking for links on them. By default, only navigation links are crawled.
+func WithFollowKinds(kinds ...fetcher.LinkKind) Option {
+	return func(c *Crawler) {
+		c.follow = make(map[fetcher.LinkKind]bool, len(kinds))
+		for _, k := range kinds {
+			c.follow[k] = true
+		}
+	}
+}
+
+// WithRespectNoFollow sets whether the crawler skips links that ask not to be followed, i.e. links with rel="nofollow"
+// and the links on pages whose meta robots or X-Robots-Tag say "nofollow". Assets, such as images, are still checked, as
+// they're part of the page rather than links away from it. Links that the crawl never reaches in another way are recorded
+// as skipped once it's done. By default, every link is followed.
+func WithRespectNoFollow(respect bool) Option {
+	return func(c *Crawler) {
+		c.respectNoFollow = respect
+	}
 }
 
-func NewCrawler(cfg *dependencies.Config, fetcher fetcher.IFetcher) *Crawler {
-	return &Crawler{
-		cfg:     cfg,
-		fetcher: fetcher,
-		Visited: make(map[string]bool),
+// WithLowMemory keeps the crawler from holding on to the URL of every link that it doesn't crawl, for crawls too large to fit
+// in memory. Links outside the crawl's scope are tracked in the given store, e.g. a BloomVisitedStore, skipped links are only
+// counted and links that ask not to be followed are recorded as soon as they're found, rather than once the crawl is done,
+// so a page that's linked to both with and without nofollow may be recorded twice. Pair it with a ResultSink that doesn't keep
+// every result, such as a CountingSink. It can't be used with checkpoints.
+func WithLowMemory(outOfScope VisitedStore) Option {
+	return func(c *Crawler) {
+		c.outOfScope = outOfScope
+		c.lowMemory = true
+	}
+}
+
+// WithResultSink sets where the result of every URL the crawler comes across is recorded. By default, results are kept in memory.
+func WithResultSink(s ResultSink) Option {
+	return func(c *Crawler) {
+		c.results = s
+	}
+}
+
+// WithGraph makes the crawler record every link it finds on the pages it fetches as an edge in g.
+func WithGraph(g graph.IGraph) Option {
+	return func(c *Crawler) {
+		c.graph = g
+	}
+}
+
+// WithCheckpoints makes RunBounded save a checkpoint to path every interval, as well as when it stops.
+func WithCheckpoints(path string, interval time.Duration) Option {
+	return func(c *Crawler) {
+		c.checkpointPath = path
+		c.checkpointInterval = interval
+	}
+}
+
+// WithRobots makes the crawler skip over any links that the site's robots.txt disallows.
+func WithRobots(r robots.IRobots) Option {
+	return func(c *Crawler) {
+		c.robots = r
+	}
+}
+
+func NewCrawler(cfg *dependencies.Config, fetcher fetcher.IFetcher, opts ...Option) *Crawler {
+	// The default normalizer has no options, so it can't fail.
+	norm, _ := urlnorm.NewNormalizer()
+
+	c := &Crawler{
+		cfg:      cfg,
+		fetcher:  fetcher,
+		frontier: NewFIFOFrontier(),
+		visited:  NewMemoryVisitedStore(),
+		Skipped:  make(map[string]string),
+		results:  NewMemorySink(),
+		scope:    &Scope{schemes: defaultSchemes},
+		norm:     norm,
+		follow:   defaultFollowKinds,
+
+		outOfScope: NewMemoryVisitedStore(),
+		noFollow:   make(map[string]*FrontierItem),
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c
+}
+
+// Run crawls from the given seed URLs until there's nothing left to visit or ctx is done.
+// It runs in bounded mode when MAX_CRAWL_CONCURRENCY_LEVEL is set, and in unbounded mode otherwise.
+func (c *Crawler) Run(ctx context.Context, seeds ...string) {
+	if c.cfg.MaxCrawlConcurrencyLevel > 0 {
+		log.Println("Running in BOUNDED mode...")
+		c.RunBounded(ctx, seeds...)
+	} else {
+		log.Println("Running in UNBOUNDED mode...")
+		c.RunUnbounded(ctx, seeds...)
 	}
 }
 
 // This function simply recurses through parsed links and spins up a goroutine for each new link to visit/crawl.
 // It'll spin up as many goroutines as possible to work on each link.
-func (c *Crawler) RunUnbounded(url string, depth int) {
-	o := c.markAsVisited(url)
-	if !o || (c.cfg.MaxCrawlDepth > 0 && depth >= c.cfg.MaxCrawlDepth) {
-		return
+// Anything left in the frontier, e.g. from a restored checkpoint, is crawled alongside the seeds.
+func (c *Crawler) RunUnbounded(ctx context.Context, seeds ...string) {
+	for _, u := range c.normalizeSeeds(seeds) {
+		c.frontier.Push(&FrontierItem{URL: u, Depth: 1})
 	}
 
-	log.Printf("visited: %s\n", url)
+	for {
+		var wg sync.WaitGroup
+		for item, ok := c.frontier.Pop(); ok; item, ok = c.frontier.Pop() {
+			wg.Add(1)
+			go func(item *FrontierItem) {
+				defer wg.Done()
+				c.crawlUnbounded(ctx, item)
+			}(item)
+		}
+		wg.Wait()
 
-	urls, err := c.fetcher.Fetch(url)
-	if err != nil {
-		log.Printf("skipping - unable to crawl %s - %v\n", url, err)
-		return
+		items := c.takeNoFollow()
+		if len(items) == 0 || ctx.Err() != nil {
+			break
+		}
+		for _, item := range items {
+			c.frontier.Push(item)
+		}
 	}
 
-	if len(urls) == 0 {
+	c.recordNoFollow()
+}
+
+func (c *Crawler) crawlUnbounded(ctx context.Context, item *FrontierItem) {
+	if ctx.Err() != nil {
 		return
 	}
 
-	c.logAttempts(urls)
+	items, _ := c.visit(ctx, item)
 
 	var wg sync.WaitGroup
-	for _, u := range urls {
+	for _, item := range items {
 		wg.Add(1)
-		go func(u string) {
+		go func(item *FrontierItem) {
 			defer wg.Done()
-			c.RunUnbounded(u, depth+1)
-		}(u)
+			c.crawlUnbounded(ctx, item)
+		}(item)
 	}
 	wg.Wait()
-
-	return
 }
 
 // This function sets a bounded limit on the amount of concurrent web-crawlers that can run at a time.
-// It uses a fan-in/fan-out approach by fanning out workers to parse links from concurrent HTTP requests and a main worker to queue up pending links that are waiting to be visited.
-func (c *Crawler) RunBounded(url string, depth int) {
+// It uses a fan-in/fan-out approach by fanning out workers to parse links from concurrent HTTP requests and a main worker that hands out pending links from the frontier.
+// The main worker keeps an exact count of the links handed out to workers, so the crawl ends as soon as the frontier is empty and nothing is in flight.
+func (c *Crawler) RunBounded(ctx context.Context, seeds ...string) {
 	type crawlJob struct {
-		url   string
-		depth int
+		id   int
+		item *FrontierItem
 	}
 
 	type pendingJob struct {
-		urls  []string
-		depth int
+		id    int // the id of the crawl job that yielded this
+		items []*FrontierItem
+		done  bool
+	}
+
+	var wg sync.WaitGroup
+
+	// Every job that a worker picks up yields exactly one pending job (possibly without any urls) in return.
+	worker := func(targetUrlCh <-chan *crawlJob, pendingUrlsCh chan<- *pendingJob) {
+		defer wg.Done()
+
+		for job := range targetUrlCh {
+			items, done := c.visit(ctx, job.item)
+			pendingUrlsCh <- &pendingJob{id: job.id, items: items, done: done}
+		}
 	}
 
 	var (
-		wg   sync.WaitGroup
-		work atomic.Int64
+		inFlight = make(map[int]*FrontierItem) // links handed out to workers that they're yet to hand back, by job id
+		next     *FrontierItem
+		nextId   int
 	)
 
-	worker := func(ctx context.Context, targetUrlCh <-chan *crawlJob, pendingUrlsCh chan<- *pendingJob) {
-		defer wg.Done()
+	push := func(items []*FrontierItem) {
+		for _, item := range items {
+			// Links that were visited since they were found are dropped here rather than taking up room in the frontier.
+			if !c.isVisited(item.URL) {
+				c.frontier.Push(item)
+			}
+		}
+	}
+
+	// Links that a worker didn't get to finish because the crawl stopped are kept in flight, so that a checkpoint puts them back into the frontier.
+	handBack := func(pending *pendingJob) {
+		if pending.done {
+			delete(inFlight, pending.id)
+		}
+		push(pending.items)
+	}
+
+	checkpoint := func() {
+		if c.checkpointPath == "" {
+			return
+		}
+
+		pending := make([]*FrontierItem, 0, len(inFlight)+1)
+		for _, item := range inFlight {
+			pending = append(pending, item)
+		}
+		if next != nil {
+			pending = append(pending, next)
+		}
+
+		if err := c.checkpoint(pending).Save(c.checkpointPath); err != nil {
+			log.Printf("unable to save checkpoint to %s - %v\n", c.checkpointPath, err)
+		}
+	}
+
+	crawl := func(targetUrlCh chan<- *crawlJob, pendingUrlsCh <-chan *pendingJob) {
+		var tick <-chan time.Time
+		if c.checkpointPath != "" && c.checkpointInterval > 0 {
+			ticker := time.NewTicker(c.checkpointInterval)
+			defer ticker.Stop()
+			tick = ticker.C
+		}
 
-	loop:
 		for {
-			select {
-			case job := <-targetUrlCh:
-				work.Add(1)
+			// Only take a link off the frontier once there's an idle worker to hand it to, so that its position stays up-to-date.
+			if next == nil && len(inFlight) < c.cfg.MaxCrawlConcurrencyLevel {
+				next, _ = c.frontier.Pop()
+			}
 
-				o := c.markAsVisited(job.url)
-				if !o || (c.cfg.MaxCrawlDepth > 0 && job.depth >= c.cfg.MaxCrawlDepth) {
-					work.Add(-1)
-					continue loop
+			if next == nil && len(inFlight) == 0 {
+				items := c.takeNoFollow()
+				if len(items) == 0 {
+					return
 				}
+				push(items)
+				continue
+			}
 
-				log.Printf("visited: %s\n", job.url)
+			// Sending on a nil channel blocks forever, which disables that case until there's a link to hand out.
+			var (
+				sendCh chan<- *crawlJob
+				job    *crawlJob
+			)
+			if next != nil {
+				sendCh = targetUrlCh
+				job = &crawlJob{id: nextId, item: next}
+			}
 
-				urls, err := c.fetcher.Fetch(job.url)
-				if err != nil {
-					log.Printf("skipping - unable to crawl %s - %v\n", job.url, err)
-					work.Add(-1)
-					continue loop
-				}
+			select {
+			case sendCh <- job:
+				inFlight[job.id] = next
+				next = nil
+				nextId++
+			case pending := <-pendingUrlsCh:
+				handBack(pending)
+			case <-tick:
+				checkpoint()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}
 
-				if len(urls) == 0 {
-					work.Add(-1)
-					continue loop
-				}
+	targetUrlCh := make(chan *crawlJob)
 
-				c.logAttempts(urls)
+	// Each worker has at most one pending job outstanding, so they never block on handing it back.
+	pendingUrlsCh := make(chan *pendingJob, c.cfg.MaxCrawlConcurrencyLevel)
 
-				pendingUrlsCh <- &pendingJob{urls: urls, depth: job.depth + 1}
+	for i := 0; i < c.cfg.MaxCrawlConcurrencyLevel; i++ {
+		wg.Add(1)
+		go worker(targetUrlCh, pendingUrlsCh)
+	}
 
-				work.Add(-1)
-			case <-ctx.Done():
-				break loop
+	seedItems := make([]*FrontierItem, 0, len(seeds))
+	for _, u := range c.normalizeSeeds(seeds) {
+		seedItems = append(seedItems, &FrontierItem{URL: u, Depth: 1})
+	}
+	push(seedItems)
+	crawl(targetUrlCh, pendingUrlsCh)
+
+	close(targetUrlCh)
+	wg.Wait()
+
+	// Collect whatever the workers finished after the crawl was stopped, so that the last checkpoint doesn't redo it.
+	close(pendingUrlsCh)
+	for pending := range pendingUrlsCh {
+		handBack(pending)
+	}
+
+	checkpoint()
+
+	// The checkpoint keeps the links that weren't followed rather than their results, so that a resumed crawl can still follow them.
+	c.recordNoFollow()
+}
+
+// visit crawls a single link and returns the links found on it that are yet to be crawled, if any.
+// It also reports whether the link was dealt with for good, which isn't the case when the crawl stopped halfway through.
+func (c *Crawler) visit(ctx context.Context, item *FrontierItem) ([]*FrontierItem, bool) {
+	if !c.markAsVisited(item.URL) {
+		return nil, true
+	}
+
+	result := &PageResult{URL: item.URL, Depth: item.Depth, Parent: item.Parent, Kind: item.Kind}
+
+	if item.CheckOnly {
+		return nil, c.check(ctx, result)
+	}
+
+	if c.traps != nil {
+		if reason, ok := c.traps.Check(item.URL); !ok {
+			log.Printf("skipping - %s is quarantined - %s\n", item.URL, reason)
+			c.markAsSkipped(item.URL, reason)
+
+			result.Status, result.Error = PageQuarantined, reason
+			c.results.Record(result)
+			return nil, true
+		}
+	}
+
+	if c.cfg.MaxCrawlDepth > 0 && item.Depth >= c.cfg.MaxCrawlDepth {
+		result.Status, result.Error = PageSkippedDepth, reasonMaxDepth
+		c.results.Record(result)
+		return nil, true
+	}
+
+	if !c.allowedByRobots(ctx, result) {
+		return nil, ctx.Err() == nil
+	}
+
+	log.Printf("visited: %s\n", item.URL)
+
+	start := time.Now()
+	page, err := c.fetcher.Fetch(ctx, item.URL)
+	result.Latency = time.Since(start)
+	if err != nil {
+		c.markAsFailed(ctx, result, err)
+		return nil, ctx.Err() == nil
+	}
+	c.fetched.Add(1)
+
+	result.Status = PageSucceeded
+	result.StatusCode = page.StatusCode
+	result.ContentType = page.ContentType
+	result.Size = page.Size
+	if !page.LastModified.IsZero() {
+		lastModified := page.LastModified
+		result.LastModified = &lastModified
+	}
+	result.Canonical = c.normalizeCanonical(page.Canonical)
+	result.NoIndex = page.NoIndex
+	result.NoFollow = page.NoFollow
+	result.Anchors = page.Anchors
+
+	if !c.followRedirects(item, result, page) {
+		c.results.Record(result)
+		return nil, true
+	}
+	c.results.Record(result)
+
+	links := c.normalizeLinks(page.Links)
+	if page.NoFollow {
+		// A nofollow page asks for the links away from it not to be followed, which doesn't include its assets.
+		for i := range links {
+			if links[i].Kind == fetcher.KindNavigation || links[i].Kind == "" {
+				links[i].NoFollow = true
 			}
 		}
 	}
 
-	crawl := func(terminator context.CancelFunc, targetUrlCh chan<- *crawlJob, pendingUrlsCh <-chan *pendingJob) {
-	loop:
-		for {
-			select {
-			case job := <-pendingUrlsCh:
-				for _, u := range job.urls {
-					targetUrlCh <- &crawlJob{url: u, depth: job.depth}
-				}
-				continue loop
-			case <-time.After(3 * time.Second): // helps to terminate all workers when there's nothing left to process.
-				log.Printf("searching for more links to process...(%d)\n", work.Load())
-				if work.Load() <= 0 {
-					break loop
-				}
+	if c.graph != nil {
+		for _, link := range links {
+			c.graph.AddEdge(graph.Edge{Source: item.URL, Target: link.URL, Text: link.Text, Position: link.Position, Fragment: link.fragment, Kind: string(link.Kind), NoFollow: link.NoFollow})
+		}
+	}
+
+	items := c.inScope(item, c.followable(item, links))
+	if len(items) == 0 {
+		return nil, true
+	}
+
+	urls := make([]string, 0, len(items))
+	for _, i := range items {
+		if !i.CheckOnly {
+			urls = append(urls, i.URL)
+		}
+	}
+	if len(urls) > 0 {
+		c.logAttempts(urls)
+	}
+
+	return items, true
+}
+
+// check makes sure that a link outside the crawl's scope or of a kind that isn't followed works, without crawling it. It
+// reports whether the link was dealt with for good, as visit does.
+func (c *Crawler) check(ctx context.Context, result *PageResult) bool {
+	if !c.allowedByRobots(ctx, result) {
+		return ctx.Err() == nil
+	}
+
+	checker := c.checker
+	if checker == nil {
+		checker = c.fetcher
+	}
+
+	start := time.Now()
+	page, err := checker.Fetch(ctx, result.URL)
+	result.Latency = time.Since(start)
+	if err != nil {
+		c.markAsFailed(ctx, result, err)
+		return ctx.Err() == nil
+	}
+
+	result.Status = PageChecked
+	result.StatusCode = page.StatusCode
+	result.ContentType = page.ContentType
+	result.Size = page.Size
+	c.recordRedirects(result, page)
+	c.results.Record(result)
+
+	return true
+}
+
+// normalizeSeeds normalizes the seed URLs, dropping any that can't be crawled.
+func (c *Crawler) normalizeSeeds(seeds []string) []string {
+	urls := make([]string, 0, len(seeds))
+	for _, u := range seeds {
+		n, err := c.norm.Normalize(u)
+		if err != nil {
+			log.Printf("skipping - unable to normalize %s: %v\n", u, err)
+			continue
+		}
+		urls = append(urls, n)
+	}
+	return urls
+}
+
+// followRedirects records the redirects that were followed to get to the page, and reports whether the links on the page
+// should be crawled. They aren't when the page that was redirected to is outside the crawl's scope, or when it was already
+// visited through another link.
+func (c *Crawler) followRedirects(item *FrontierItem, result *PageResult, page *fetcher.Page) bool {
+	if !c.recordRedirects(result, page) {
+		return true
+	}
+
+	if rule, ok := c.scope.Check(item.URL, result.FinalURL); !ok {
+		log.Printf("skipping - %s redirects out of scope to %s - %s\n", item.URL, result.FinalURL, rule)
+		result.Status, result.Error = PageOutOfScope, reasonRedirectOutOfScope+rule
+		return false
+	}
+
+	// The page that was redirected to is visited as well, so that it isn't crawled again when it's linked to directly.
+	return result.FinalURL == item.URL || c.markAsVisited(result.FinalURL)
+}
+
+// normalizeCanonical normalizes a page's canonical URL the same way as the URLs it's compared with, so that a page that
+// declares itself as canonical, e.g. without its trailing slash, is taken as canonical.
+func (c *Crawler) normalizeCanonical(canonical string) string {
+	if canonical == "" {
+		return ""
+	}
+	if n, err := c.norm.Normalize(canonical); err == nil {
+		return n
+	}
+	return canonical
+}
+
+// recordRedirects adds the page's redirect chain and final URL to the result, and reports whether there were any.
+func (c *Crawler) recordRedirects(result *PageResult, page *fetcher.Page) bool {
+	if len(page.Redirects) == 0 {
+		return false
+	}
+
+	result.Redirects = page.Redirects
+	result.FinalURL = page.URL
+	if final, err := c.norm.Normalize(page.URL); err == nil {
+		result.FinalURL = final
+	}
+	return true
+}
+
+// pageLink is a normalized link found on a page, along with the fragment that normalizing it dropped.
+type pageLink struct {
+	fetcher.Link
+	fragment string
+}
+
+// normalizeLinks normalizes the links found on a page. Links that only differ in their fragment, e.g. /a#top and
+// /a#install, are all kept so that their fragments can be checked, even though they're the same page.
+func (c *Crawler) normalizeLinks(links []fetcher.Link) []pageLink {
+	found := make(map[string]bool, len(links))
+	normalized := make([]pageLink, 0, len(links))
+	for _, link := range links {
+		u, err := c.norm.Normalize(link.URL)
+		if err != nil {
+			log.Printf("skipping - unable to normalize %s: %v\n", link.URL, err)
+			continue
+		}
+
+		// Fragments are compared with the page's anchors as they are, rather than percent-encoded.
+		var fragment string
+		if parsed, err := url.Parse(link.URL); err == nil {
+			fragment = parsed.Fragment
+		}
+
+		// Links that normalize to the same page and have the same fragment are only kept the first time they appear.
+		key := u + "#" + fragment
+		if found[key] {
+			continue
+		}
+		found[key] = true
+
+		link.URL = u
+		normalized = append(normalized, pageLink{Link: link, fragment: fragment})
+	}
+	return normalized
+}
+
+// followable sets aside the links that ask not to be followed when the crawler respects that, and returns the rest.
+func (c *Crawler) followable(item *FrontierItem, links []pageLink) []pageLink {
+	if !c.respectNoFollow {
+		return links
+	}
+
+	followable := make([]pageLink, 0, len(links))
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	for _, link := range links {
+		if !link.NoFollow {
+			followable = append(followable, link)
+			continue
+		}
+		if c.lowMemory {
+			if c.outOfScope.MarkIfNew(link.URL) && !c.isVisited(link.URL) {
+				c.results.Record(&PageResult{URL: link.URL, Status: PageNoFollow, Depth: item.Depth + 1, Parent: item.URL, Kind: link.Kind, Error: reasonNoFollow})
 			}
+			continue
+		}
+		if _, ok := c.noFollow[link.URL]; !ok {
+			c.noFollow[link.URL] = &FrontierItem{URL: link.URL, Depth: item.Depth + 1, Parent: item.URL, Kind: link.Kind}
 		}
+	}
+	return followable
+}
 
-		terminator()
+// takeNoFollow hands back the links that weren't followed because they asked not to be, and that the crawl didn't get to in
+// another way, to be checked only. Checking a link isn't following it, so a link checker still makes sure they work. It's
+// called once there's nothing else left to crawl, so that pages that are also linked to without nofollow get crawled first.
+func (c *Crawler) takeNoFollow() []*FrontierItem {
+	if !c.checkOutOfScope {
+		return nil
 	}
 
-	targetUrlCh := make(chan *crawlJob)
-	defer close(targetUrlCh)
+	c.lock.Lock()
+	defer c.lock.Unlock()
 
-	pendingUrlsCh := make(chan *pendingJob, 100_000) // Buffered channel to limit the no. of pending unprocessed links at a time.
-	defer close(pendingUrlsCh)
+	items := make([]*FrontierItem, 0, len(c.noFollow))
+	for url, item := range c.noFollow {
+		delete(c.noFollow, url)
+		if !c.isVisited(url) {
+			item.CheckOnly = true
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].URL < items[j].URL
+	})
 
-	ctx, terminator := context.WithCancel(context.Background())
+	return items
+}
 
-	for i := 0; i < c.cfg.MaxCrawlConcurrencyLevel; i++ {
-		wg.Add(1)
-		go worker(ctx, targetUrlCh, pendingUrlsCh)
+// recordNoFollow records the links that weren't followed because they asked not to be, unless the crawl got to them in
+// another way.
+func (c *Crawler) recordNoFollow() {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	items := make([]*FrontierItem, 0, len(c.noFollow))
+	for _, item := range c.noFollow {
+		if !c.isVisited(item.URL) && !c.outOfScope.Has(item.URL) {
+			items = append(items, item)
+		}
+	}
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].URL < items[j].URL
+	})
+
+	for _, item := range items {
+		c.results.Record(&PageResult{URL: item.URL, Status: PageNoFollow, Depth: item.Depth, Parent: item.Parent, Kind: item.Kind, Error: reasonNoFollow})
 	}
+}
 
-	// Optional: We could potentially make this function run concurrently as well if we wanted to optimise further.
-	go crawl(terminator, targetUrlCh, pendingUrlsCh)
+// inScope returns the links that the crawler's scope lets it follow from the page they were found on. Those of a kind that
+// isn't followed are returned to be checked only.
+// The others are recorded as out of scope the first time they're found, along with the rule that rejected them. When
+// there's a link checker, they're returned to be checked instead.
+func (c *Crawler) inScope(item *FrontierItem, links []pageLink) []*FrontierItem {
+	found := make(map[string]bool, len(links))
+	items := make([]*FrontierItem, 0, len(links))
+	for _, link := range links {
+		if found[link.URL] {
+			continue
+		}
+		found[link.URL] = true
 
-	pendingUrlsCh <- &pendingJob{urls: []string{url}, depth: depth}
+		next := &FrontierItem{URL: link.URL, Depth: item.Depth + 1, Parent: item.URL, Kind: link.Kind}
 
-	wg.Wait()
+		rule, ok := c.scope.Check(item.URL, link.URL)
+		if ok {
+			next.CheckOnly = !c.follows(link.Kind)
+			items = append(items, next)
+			continue
+		}
+
+		if !c.outOfScope.MarkIfNew(link.URL) {
+			continue
+		}
+
+		if c.checkOutOfScope {
+			next.CheckOnly = true
+			items = append(items, next)
+		} else {
+			c.results.Record(&PageResult{URL: link.URL, Status: PageOutOfScope, Depth: next.Depth, Parent: item.URL, Kind: link.Kind, Error: rule})
+		}
+	}
+
+	return items
+}
+
+// follows reports whether links of the given kind are crawled. Links from fetchers that don't tell kinds apart are taken
+// as navigation links.
+func (c *Crawler) follows(kind fetcher.LinkKind) bool {
+	return kind == "" || c.follow[kind]
+}
+
+// Results returns where the crawler records the result of every URL it comes across.
+func (c *Crawler) Results() ResultSink {
+	return c.results
+}
+
+// Fetched returns the no. of pages that the crawler has fetched so far.
+func (c *Crawler) Fetched() int64 {
+	return c.fetched.Load()
+}
+
+// Visited returns the links that the crawler has visited so far.
+func (c *Crawler) Visited() VisitedStore {
+	return c.visited
+}
+
+func (c *Crawler) isVisited(url string) bool {
+	return c.visited.Has(url)
 }
 
 func (c *Crawler) markAsVisited(url string) bool {
+	return c.visited.MarkIfNew(url)
+}
+
+func (c *Crawler) markAsSkipped(url string, reason string) {
+	if c.lowMemory {
+		c.skipped.Add(1)
+		return
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	c.Skipped[url] = reason
+}
+
+// SkippedLen returns the no. of visited links that weren't crawled.
+func (c *Crawler) SkippedLen() int {
+	if c.lowMemory {
+		return int(c.skipped.Load())
+	}
+
 	c.lock.Lock()
 	defer c.lock.Unlock()
 
-	_, ok := c.Visited[url]
-	if ok {
+	return len(c.Skipped)
+}
+
+// markAsFailed records why a link couldn't be crawled, telling apart errors that may go away on their own from ones that won't.
+func (c *Crawler) markAsFailed(ctx context.Context, result *PageResult, err error) {
+	// Fetches cut short by the crawl being cancelled aren't the page's fault.
+	if ctx.Err() != nil {
+		log.Printf("skipping - crawl stopped before %s could be fetched\n", result.URL)
+		c.markAsStopped(result)
+		return
+	}
+
+	var statusErr *fetcher.HTTPStatusError
+	if errors.As(err, &statusErr) {
+		result.StatusCode = statusErr.StatusCode
+	}
+	var redirectErr *fetcher.RedirectError
+	if errors.As(err, &redirectErr) {
+		result.Redirects = redirectErr.Redirects
+	}
+
+	class := fetcher.Classify(err)
+	reason := fmt.Sprintf("%s error: %v", class, err)
+	log.Printf("skipping - unable to crawl %s (%s) - %v\n", result.URL, class, err)
+	c.markAsSkipped(result.URL, reason)
+
+	result.Status, result.Error = PageFailed, reason
+	c.results.Record(result)
+}
+
+func (c *Crawler) markAsStopped(result *PageResult) {
+	c.markAsSkipped(result.URL, reasonCrawlStopped)
+
+	result.Status, result.Error = PageStopped, reasonCrawlStopped
+	c.results.Record(result)
+}
+
+func (c *Crawler) allowedByRobots(ctx context.Context, result *PageResult) bool {
+	if c.robots == nil || c.robots.Allowed(ctx, result.URL) {
+		return true
+	}
+
+	// robots.txt can't be fetched once the crawl is stopped, which isn't the same as the site blocking us.
+	if ctx.Err() != nil {
+		c.markAsStopped(result)
 		return false
 	}
-	c.Visited[url] = true
 
-	return true
+	log.Printf("skipping - %s is %s\n", result.URL, reasonBlockedByRobots)
+	c.markAsSkipped(result.URL, reasonBlockedByRobots)
+
+	result.Status, result.Error = PageBlocked, reasonBlockedByRobots
+	c.results.Record(result)
+
+	return false
 }
 
 func (c *Crawler) logAttempts(urls []string) {
@@ -1,16 +1,683 @@
 package crawler
 
 import (
+	"context"
+	"fmt"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 	"webcrawler-go/internal/dependencies"
 	"webcrawler-go/internal/fetcher"
+	"webcrawler-go/internal/graph"
+	"webcrawler-go/internal/robots"
+	"webcrawler-go/internal/urlnorm"
 )
 
+func visitedUrls(c *Crawler) []string {
+	var urls []string
+	c.Visited().Iterate(func(url string) bool {
+		urls = append(urls, url)
+		return true
+	})
+	return urls
+}
+
+// blockingFetcher hangs on every URL other than the seed until the crawl is cancelled.
+type blockingFetcher struct {
+	seed string
+}
+
+func (f blockingFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	if targetUrl == f.seed {
+		return &fetcher.Page{Links: []fetcher.Link{{URL: f.seed + "slow/"}}}, nil
+	}
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// concurrencyFetcher links the seed to fanOut slow pages and keeps track of the most fetches that ran at the same time.
+type concurrencyFetcher struct {
+	fanOut    int
+	delay     time.Duration
+	active    atomic.Int64
+	maxActive atomic.Int64
+}
+
+func (f *concurrencyFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	active := f.active.Add(1)
+	defer f.active.Add(-1)
+
+	for {
+		m := f.maxActive.Load()
+		if active <= m || f.maxActive.CompareAndSwap(m, active) {
+			break
+		}
+	}
+
+	time.Sleep(f.delay)
+
+	if targetUrl != "https://monzo.com/" {
+		return &fetcher.Page{}, nil
+	}
+
+	links := make([]fetcher.Link, f.fanOut)
+	for i := range links {
+		links[i] = fetcher.Link{URL: fmt.Sprintf("https://monzo.com/%d/", i)}
+	}
+	return &fetcher.Page{Links: links}, nil
+}
+
+// variantsFetcher links the seed to several ways of writing the same page, and counts how often each URL is fetched.
+type variantsFetcher struct {
+	fetches sync.Map
+}
+
+func (f *variantsFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	n, _ := f.fetches.LoadOrStore(targetUrl, new(atomic.Int64))
+	n.(*atomic.Int64).Add(1)
+
+	if targetUrl != "https://monzo.com/" {
+		return &fetcher.Page{}, nil
+	}
+	return &fetcher.Page{Links: []fetcher.Link{
+		{URL: "https://monzo.com/a", Position: 1},
+		{URL: "https://monzo.com/a/", Position: 2},
+		{URL: "https://monzo.com/a#top", Position: 3},
+		{URL: "https://monzo.com/b/../A/../a?utm_source=x", Position: 4},
+		{URL: "HTTPS://Monzo.com:443/a", Position: 5},
+	}}, nil
+}
+
+// deepeningFetcher links every page to a page one level deeper, as relative links that are resolved against the wrong
+// base do, so the crawl never ends on its own.
+type deepeningFetcher struct{}
+
+func (f deepeningFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return &fetcher.Page{Links: []fetcher.Link{{URL: targetUrl + "a/"}}}, nil
+}
+
+// statusFetcher responds to the URLs it knows about with their status code, and fails the rest as not found.
+type statusFetcher map[string]int
+
+func (f statusFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	if status, ok := f[targetUrl]; ok {
+		return &fetcher.Page{StatusCode: status}, nil
+	}
+	return nil, &fetcher.HTTPStatusError{StatusCode: http.StatusNotFound, URL: targetUrl}
+}
+
+// orderFetcher records the order in which links are fetched. It's only safe to use with a single worker.
+type orderFetcher struct {
+	fetcher fetcher.IFetcher
+	order   []string
+}
+
+func (f *orderFetcher) Fetch(ctx context.Context, targetUrl string) (*fetcher.Page, error) {
+	f.order = append(f.order, targetUrl)
+	return f.fetcher.Fetch(ctx, targetUrl)
+}
+
+func TestCrawler_Run(t *testing.T) {
+	os.Setenv("APP_ENV", "test")
+
+	for _, concurrency := range []int{-1, 5} {
+		t.Run(fmt.Sprintf("stops when the deadline passes (concurrency %d)", concurrency), func(t *testing.T) {
+			cfg := dependencies.LoadEnv()
+			cfg.MaxCrawlConcurrencyLevel = concurrency
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+
+			c := NewCrawler(cfg, blockingFetcher{seed: "https://monzo.com/"})
+
+			start := time.Now()
+			c.Run(ctx, "https://monzo.com/")
+
+			assert.Less(t, time.Since(start), 5*time.Second)
+			assert.Equal(t, map[string]string{"https://monzo.com/slow/": "crawl stopped"}, c.Skipped)
+		})
+	}
+
+	t.Run("crawls from every seed", func(t *testing.T) {
+		cfg := dependencies.LoadEnv()
+		cfg.MaxCrawlDepth = 2
+
+		c := NewCrawler(cfg, fetcher.NewMockFetcher())
+		c.Run(context.Background(), "https://monzo.com/switch/", "https://monzo.com/monzo-plus/")
+
+		urls := visitedUrls(c)
+
+		assert.ElementsMatch(t, []string{
+			"https://monzo.com/",
+			"https://monzo.com/current-account/",
+			"https://monzo.com/switch/",
+			"https://monzo.com/monzo-plus/",
+		}, urls)
+	})
+}
+
+// resultsByUrl summarises the recorded results as "status depth parent" per URL.
+func resultsByUrl(c *Crawler) map[string]string {
+	results := make(map[string]string)
+	for _, r := range c.Results().(*MemorySink).Results() {
+		results[r.URL] = fmt.Sprintf("%s %d %s", r.Status, r.Depth, r.Parent)
+	}
+	return results
+}
+
+func TestCrawler_Results(t *testing.T) {
+	os.Setenv("APP_ENV", "test")
+
+	for _, concurrency := range []int{-1, 5} {
+		t.Run(fmt.Sprintf("records the result of every URL (concurrency %d)", concurrency), func(t *testing.T) {
+			cfg := dependencies.LoadEnv()
+			cfg.MaxCrawlConcurrencyLevel = concurrency
+
+			r := robots.NewMockRobots("https://monzo.com/switch/")
+			c := NewCrawler(cfg, fetcher.NewMockFetcher(), WithRobots(r))
+			c.Run(context.Background(), "https://monzo.com/")
+
+			assert.Equal(t, map[string]string{
+				"https://monzo.com/":                               "succeeded 1 ",
+				"https://monzo.com/current-account/":               "succeeded 2 https://monzo.com/",
+				"https://monzo.com/monzo-plus/":                    "succeeded 2 https://monzo.com/",
+				"https://twitter.com/monzo":                        "out-of-scope 2 https://monzo.com/",
+				"https://monzo.com/current-account/joint-account/": "succeeded 3 https://monzo.com/current-account/",
+				"https://monzo.com/help/":                          "failed 3 https://monzo.com/current-account/",
+				"https://monzo.com/switch/":                        "blocked 3 https://monzo.com/current-account/",
+			}, resultsByUrl(c))
+			assert.Equal(t, 7, c.Results().(*MemorySink).Len(), "every URL should be recorded once")
+
+			for _, result := range c.Results().(*MemorySink).Results() {
+				switch result.Status {
+				case PageSucceeded:
+					assert.Equal(t, 200, result.StatusCode)
+					assert.Equal(t, "text/html; charset=utf-8", result.ContentType)
+				case PageFailed:
+					assert.Equal(t, "permanent error: cannot parse any urls from: https://monzo.com/help/", result.Error)
+				case PageBlocked:
+					assert.Equal(t, reasonBlockedByRobots, result.Error)
+				}
+			}
+		})
+	}
+
+	t.Run("records every link found as an edge", func(t *testing.T) {
+		cfg := dependencies.LoadEnv()
+
+		g := graph.NewGraph()
+		c := NewCrawler(cfg, fetcher.NewMockFetcher(), WithGraph(g))
+		c.Run(context.Background(), "https://monzo.com/")
+
+		edges := g.Edges()
+		assert.Len(t, edges, 11)
+		assert.Equal(t, []graph.Edge{
+			{Source: "https://monzo.com/", Target: "https://monzo.com/current-account/", Position: 1, Kind: "navigation"},
+			{Source: "https://monzo.com/", Target: "https://monzo.com/monzo-plus/", Position: 2, Kind: "navigation"},
+			{Source: "https://monzo.com/", Target: "https://twitter.com/monzo", Position: 3, Kind: "navigation"},
+		}, edges[:3])
+	})
+
+	t.Run("normalizes the canonical url of pages", func(t *testing.T) {
+		var testServer *httptest.Server
+		testServer = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, `<link rel="canonical" href="%s?utm_source=home">`, strings.Replace(testServer.URL, "http://", "HTTP://", 1))
+		}))
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+
+		norm, err := urlnorm.NewNormalizer(urlnorm.WithStripParams("utm_*"))
+		require.NoError(t, err)
+		c := NewCrawler(cfg, fetcher.NewFetcher(), WithNormalizer(norm))
+		c.Run(context.Background(), testServer.URL)
+
+		results := c.Results().(*MemorySink).Results()
+		require.Len(t, results, 1)
+		assert.Equal(t, testServer.URL+"/", results[0].URL)
+		assert.Equal(t, results[0].URL, results[0].Canonical, "a self-canonical without a trailing slash should match the page")
+	})
+
+	t.Run("records the anchors of pages and the fragments of links", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<h2 id="install">Install</h2><a href="#install">Install</a><a href="/#usage">Usage</a><a href="/">Home</a>`)
+		}))
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+
+		g := graph.NewGraph()
+		c := NewCrawler(cfg, fetcher.NewFetcher(), WithGraph(g))
+		c.Run(context.Background(), testServer.URL)
+
+		results := c.Results().(*MemorySink).Results()
+		require.Len(t, results, 1, "links to the same page with different fragments should only be crawled once")
+		assert.Equal(t, []string{"install"}, results[0].Anchors)
+
+		home := testServer.URL + "/"
+		assert.Equal(t, []graph.Edge{
+			{Source: home, Target: home, Text: "Install", Position: 1, Fragment: "install", Kind: "navigation"},
+			{Source: home, Target: home, Text: "Usage", Position: 2, Fragment: "usage", Kind: "navigation"},
+			{Source: home, Target: home, Text: "Home", Position: 3, Kind: "navigation"},
+		}, g.Edges())
+	})
+
+	t.Run("checks assets without crawling them unless their kind is followed", func(t *testing.T) {
+		var (
+			methods []string
+			lock    sync.Mutex
+		)
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprint(w, `<a href="/about/">About</a><img src="/logo.svg" alt="Logo"><img src="/missing.png"><iframe src="/embed/"></iframe>`)
+		})
+		mux.HandleFunc("/logo.svg", func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			methods = append(methods, r.Method)
+			lock.Unlock()
+			w.Header().Set("Content-Type", "image/svg+xml")
+			fmt.Fprint(w, `<svg><a href="/hidden/"></a></svg>`)
+		})
+		mux.HandleFunc("/embed/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="/embedded/">Embedded</a>`)
+		})
+		for _, p := range []string{"/about/", "/embedded/"} {
+			mux.HandleFunc(p, func(w http.ResponseWriter, r *http.Request) {})
+		}
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+
+		f := fetcher.NewFetcher(fetcher.WithLinkKinds(fetcher.LinkKinds...))
+		c := NewCrawler(cfg, f, WithAssetChecker(fetcher.NewChecker(f)), WithFollowKinds(fetcher.KindNavigation, fetcher.KindIframe))
+		c.Run(context.Background(), testServer.URL)
+
+		results := make(map[string]*PageResult)
+		for _, r := range c.Results().(*MemorySink).Results() {
+			results[strings.TrimPrefix(r.URL, testServer.URL)] = r
+		}
+
+		require.Contains(t, results, "/logo.svg")
+		assert.Equal(t, PageChecked, results["/logo.svg"].Status)
+		assert.Equal(t, fetcher.KindImage, results["/logo.svg"].Kind)
+		assert.Equal(t, []string{http.MethodHead}, methods, "assets should only be checked")
+		assert.NotContains(t, results, "/hidden/", "links on assets shouldn't be followed")
+
+		require.Contains(t, results, "/missing.png")
+		assert.Equal(t, PageFailed, results["/missing.png"].Status)
+		assert.Equal(t, http.StatusNotFound, results["/missing.png"].StatusCode)
+
+		require.Contains(t, results, "/embed/")
+		assert.Equal(t, PageSucceeded, results["/embed/"].Status)
+		assert.Equal(t, fetcher.KindIframe, results["/embed/"].Kind)
+		require.Contains(t, results, "/embedded/", "links on iframes should be followed when they're enabled")
+		assert.Equal(t, fetcher.KindNavigation, results["/embedded/"].Kind)
+	})
+
+	t.Run("finds the imports and images of stylesheets when they're followed", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/" {
+				http.NotFound(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<link rel="stylesheet" href="/css/main.css"><div style="background: url(/img/inline.png)"></div>`)
+		})
+		mux.HandleFunc("/css/main.css", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/css")
+			fmt.Fprint(w, `@import "print.css"; body { background: url(../img/body.png); }`)
+		})
+		mux.HandleFunc("/css/print.css", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/css")
+		})
+		mux.HandleFunc("/img/body.png", func(w http.ResponseWriter, r *http.Request) {})
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+
+		f := fetcher.NewFetcher(fetcher.WithLinkKinds(fetcher.KindNavigation, fetcher.KindStylesheet, fetcher.KindImage))
+		c := NewCrawler(cfg, f, WithAssetChecker(fetcher.NewChecker(f)), WithFollowKinds(fetcher.KindNavigation, fetcher.KindStylesheet))
+		c.Run(context.Background(), testServer.URL)
+
+		statuses := make(map[string]PageStatus)
+		kinds := make(map[string]fetcher.LinkKind)
+		for _, r := range c.Results().(*MemorySink).Results() {
+			u := strings.TrimPrefix(r.URL, testServer.URL)
+			statuses[u], kinds[u] = r.Status, r.Kind
+		}
+
+		assert.Equal(t, map[string]PageStatus{
+			"/":               PageSucceeded,
+			"/css/main.css":   PageSucceeded,
+			"/css/print.css":  PageSucceeded,
+			"/img/body.png":   PageChecked,
+			"/img/inline.png": PageFailed,
+		}, statuses)
+		assert.Equal(t, fetcher.KindStylesheet, kinds["/css/print.css"])
+		assert.Equal(t, fetcher.KindImage, kinds["/img/body.png"])
+	})
+
+	for _, concurrency := range []int{-1, 5} {
+		t.Run(fmt.Sprintf("skips links that ask not to be followed (concurrency %d)", concurrency), func(t *testing.T) {
+			var (
+				hits = make(map[string]int)
+				lock sync.Mutex
+			)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				hits[r.URL.Path]++
+				lock.Unlock()
+
+				switch r.URL.Path {
+				case "/":
+					fmt.Fprint(w, `<a href="/about/">About</a><a href="/ads/" rel="nofollow">Ad</a><a href="/shared/" rel="nofollow">Shared</a><a href="/private/">Private</a>`)
+				case "/about/":
+					fmt.Fprint(w, `<a href="/shared/">Shared</a>`)
+				case "/private/":
+					w.Header().Set("X-Robots-Tag", "noindex")
+					w.Header().Set("Content-Type", "text/html")
+					fmt.Fprint(w, `<meta name="robots" content="nofollow"><a href="/secret/">Secret</a><img src="/logo.png">`)
+				}
+			})
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cfg := dependencies.LoadEnv()
+			cfg.MaxCrawlConcurrencyLevel = concurrency
+
+			f := fetcher.NewFetcher(fetcher.WithLinkKinds(fetcher.KindNavigation, fetcher.KindImage))
+			c := NewCrawler(cfg, f, WithAssetChecker(fetcher.NewChecker(f)), WithRespectNoFollow(true))
+			c.Run(context.Background(), testServer.URL)
+
+			results := make(map[string]*PageResult)
+			for _, r := range c.Results().(*MemorySink).Results() {
+				results[strings.TrimPrefix(r.URL, testServer.URL)] = r
+			}
+
+			require.Len(t, results, 7)
+			assert.Equal(t, PageSucceeded, results["/shared/"].Status, "links that are also followable should be crawled")
+			assert.True(t, results["/private/"].NoIndex)
+			assert.True(t, results["/private/"].NoFollow)
+			assert.Equal(t, PageChecked, results["/logo.png"].Status, "assets of nofollow pages should still be checked")
+
+			for _, u := range []string{"/ads/", "/secret/"} {
+				assert.Equal(t, PageNoFollow, results[u].Status)
+				assert.Equal(t, reasonNoFollow, results[u].Error)
+				assert.Zero(t, hits[u], "%s shouldn't be fetched", u)
+			}
+			assert.Equal(t, testServer.URL+"/private/", results["/secret/"].Parent)
+		})
+	}
+
+	for _, concurrency := range []int{-1, 5} {
+		t.Run(fmt.Sprintf("checks links that ask not to be followed with a link checker (concurrency %d)", concurrency), func(t *testing.T) {
+			var (
+				methods = make(map[string][]string)
+				lock    sync.Mutex
+			)
+			mux := http.NewServeMux()
+			mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+				lock.Lock()
+				methods[r.URL.Path] = append(methods[r.URL.Path], r.Method)
+				lock.Unlock()
+
+				switch r.URL.Path {
+				case "/":
+					fmt.Fprint(w, `<a href="/ads/" rel="nofollow">Ad</a><a href="/shared/" rel="nofollow">Shared</a><a href="/about/">About</a>`)
+				case "/about/":
+					fmt.Fprint(w, `<a href="/shared/">Shared</a>`)
+				case "/shared/":
+				case "/ads/":
+					http.NotFound(w, r)
+				}
+			})
+			testServer := httptest.NewServer(mux)
+			defer testServer.Close()
+
+			cfg := dependencies.LoadEnv()
+			cfg.MaxCrawlConcurrencyLevel = concurrency
+
+			f := fetcher.NewFetcher()
+			c := NewCrawler(cfg, f, WithLinkChecker(fetcher.NewChecker(f)), WithRespectNoFollow(true))
+			c.Run(context.Background(), testServer.URL)
+
+			results := make(map[string]*PageResult)
+			for _, r := range c.Results().(*MemorySink).Results() {
+				results[strings.TrimPrefix(r.URL, testServer.URL)] = r
+			}
+
+			require.Len(t, results, 4)
+			assert.Equal(t, PageSucceeded, results["/shared/"].Status, "links that are also followable should be crawled")
+			assert.Equal(t, []string{http.MethodGet}, methods["/shared/"])
+			assert.Equal(t, PageFailed, results["/ads/"].Status, "nofollow links should still be checked")
+			assert.Equal(t, http.MethodHead, methods["/ads/"][0], "nofollow links should only be checked")
+		})
+	}
+
+	t.Run("follows every link by default", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/" {
+				fmt.Fprint(w, `<a href="/ads/" rel="nofollow">Ad</a>`)
+			}
+		}))
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+
+		g := graph.NewGraph()
+		c := NewCrawler(cfg, fetcher.NewFetcher(), WithGraph(g))
+		c.Run(context.Background(), testServer.URL)
+
+		results := c.Results().(*MemorySink).Results()
+		require.Len(t, results, 2)
+		assert.Equal(t, PageSucceeded, results[1].Status)
+		assert.True(t, g.Edges()[0].NoFollow)
+	})
+
+	t.Run("records redirects and visits the pages they lead to", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="/old/">Old</a><a href="/new/">New</a><a href="/away/">Away</a><a href="/loop/">Loop</a>`)
+		})
+		mux.Handle("/old/", http.RedirectHandler("/older/", http.StatusMovedPermanently))
+		mux.Handle("/older/", http.RedirectHandler("/new/", http.StatusFound))
+		mux.HandleFunc("/new/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="/">Home</a>`)
+		})
+		mux.Handle("/away/", http.RedirectHandler("/outside/", http.StatusMovedPermanently))
+		mux.HandleFunc("/outside/", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<a href="/outside/more/">More</a>`)
+		})
+		mux.Handle("/loop/", http.RedirectHandler("/loop/", http.StatusFound))
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+		cfg.MaxCrawlConcurrencyLevel = 1
+
+		scope, err := NewScope(WithExcludePatterns("/outside/"))
+		require.NoError(t, err)
+
+		c := NewCrawler(cfg, fetcher.NewFetcher(), WithScope(scope))
+		c.Run(context.Background(), testServer.URL)
+
+		results := make(map[string]*PageResult)
+		for _, r := range c.Results().(*MemorySink).Results() {
+			results[strings.TrimPrefix(r.URL, testServer.URL)] = r
+		}
+
+		old := results["/old/"]
+		require.NotNil(t, old)
+		assert.Equal(t, PageSucceeded, old.Status)
+		assert.Equal(t, testServer.URL+"/new/", old.FinalURL)
+		assert.Equal(t, []fetcher.Redirect{
+			{URL: testServer.URL + "/old/", StatusCode: http.StatusMovedPermanently},
+			{URL: testServer.URL + "/older/", StatusCode: http.StatusFound},
+		}, old.Redirects)
+		assert.Contains(t, visitedUrls(c), testServer.URL+"/new/")
+		assert.NotContains(t, results, "/older/", "the hops in between aren't visited")
+
+		away := results["/away/"]
+		require.NotNil(t, away)
+		assert.Equal(t, PageOutOfScope, away.Status)
+		assert.Equal(t, "redirected out of scope - exclude: matches /outside/", away.Error)
+		assert.NotContains(t, results, "/outside/more/", "links on pages outside the scope aren't followed")
+
+		loop := results["/loop/"]
+		require.NotNil(t, loop)
+		assert.Equal(t, PageFailed, loop.Status)
+		assert.Contains(t, loop.Error, "redirect loop")
+		assert.Len(t, loop.Redirects, 1)
+
+		fetches := 0
+		for _, r := range results {
+			if r.URL == testServer.URL+"/new/" || r.FinalURL == testServer.URL+"/new/" {
+				fetches++
+			}
+		}
+		assert.Equal(t, 1, fetches, "/new/ should only be crawled once, whether it's linked to directly or through a redirect")
+	})
+
+	t.Run("records pages past the max depth as skipped", func(t *testing.T) {
+		cfg := dependencies.LoadEnv()
+		cfg.MaxCrawlDepth = 2
+
+		c := NewCrawler(cfg, fetcher.NewMockFetcher())
+		c.Run(context.Background(), "https://monzo.com/")
+
+		results := resultsByUrl(c)
+		assert.Equal(t, "skipped-depth 2 https://monzo.com/", results["https://monzo.com/current-account/"])
+		assert.Equal(t, "skipped-depth 2 https://monzo.com/", results["https://monzo.com/monzo-plus/"])
+	})
+
+	t.Run("records the scope rule that rejected a link", func(t *testing.T) {
+		cfg := dependencies.LoadEnv()
+
+		scope, err := NewScope(WithExcludePatterns("/help/$"))
+		require.NoError(t, err)
+
+		c := NewCrawler(cfg, fetcher.NewMockFetcher(), WithScope(scope))
+		c.Run(context.Background(), "https://monzo.com/")
+
+		errs := make(map[string]string)
+		for _, r := range c.Results().(*MemorySink).Results() {
+			if r.Status == PageOutOfScope {
+				errs[r.URL] = r.Error
+			}
+		}
+		assert.Equal(t, map[string]string{
+			"https://twitter.com/monzo": "host: twitter.com isn't on the same site as https://monzo.com/",
+			"https://monzo.com/help/":   "exclude: matches /help/$",
+		}, errs)
+	})
+
+	t.Run("normalizes links before checking whether they were visited", func(t *testing.T) {
+		cfg := dependencies.LoadEnv()
+
+		norm, err := urlnorm.NewNormalizer(urlnorm.WithStripParams("utm_*"), urlnorm.WithTrailingSlash(urlnorm.TrailingSlashStrip))
+		require.NoError(t, err)
+
+		f := &variantsFetcher{}
+		c := NewCrawler(cfg, f, WithNormalizer(norm))
+		c.Run(context.Background(), "HTTPS://MONZO.COM")
+
+		assert.ElementsMatch(t, []string{"https://monzo.com/", "https://monzo.com/a"}, visitedUrls(c))
+
+		fetches := make(map[string]int64)
+		f.fetches.Range(func(k, v any) bool {
+			fetches[k.(string)] = v.(*atomic.Int64).Load()
+			return true
+		})
+		assert.Equal(t, map[string]int64{"https://monzo.com/": 1, "https://monzo.com/a": 1}, fetches)
+	})
+
+	for _, concurrency := range []int{-1, 5} {
+		t.Run(fmt.Sprintf("quarantines links that look like a crawler trap (concurrency %d)", concurrency), func(t *testing.T) {
+			cfg := dependencies.LoadEnv()
+			cfg.MaxCrawlConcurrencyLevel = concurrency
+
+			c := NewCrawler(cfg, deepeningFetcher{}, WithTrapDetector(NewTrapDetector(WithMaxRepeatedSegments(3))))
+			c.Run(context.Background(), "https://monzo.com/")
+
+			assert.Equal(t, map[string]string{
+				"https://monzo.com/":         "succeeded 1 ",
+				"https://monzo.com/a/":       "succeeded 2 https://monzo.com/",
+				"https://monzo.com/a/a/":     "succeeded 3 https://monzo.com/a/",
+				"https://monzo.com/a/a/a/":   "succeeded 4 https://monzo.com/a/a/",
+				"https://monzo.com/a/a/a/a/": "quarantined 5 https://monzo.com/a/a/a/",
+			}, resultsByUrl(c))
+			assert.Equal(t, `trap: path segment "a" appears more than 3 times`, c.Skipped["https://monzo.com/a/a/a/a/"])
+		})
+
+		t.Run(fmt.Sprintf("checks links outside the scope without crawling them (concurrency %d)", concurrency), func(t *testing.T) {
+			cfg := dependencies.LoadEnv()
+			cfg.MaxCrawlConcurrencyLevel = concurrency
+
+			checker := statusFetcher{"https://twitter.com/monzo": http.StatusOK}
+			c := NewCrawler(cfg, fetcher.NewMockFetcher(), WithLinkChecker(checker))
+			c.Run(context.Background(), "https://monzo.com/")
+
+			results := resultsByUrl(c)
+			assert.Len(t, results, 7)
+			assert.Equal(t, "checked 2 https://monzo.com/", results["https://twitter.com/monzo"])
+			assert.Equal(t, "failed 3 https://monzo.com/current-account/", results["https://monzo.com/help/"])
+			assert.Contains(t, visitedUrls(c), "https://twitter.com/monzo")
+		})
+	}
+
+	t.Run("reports links outside the scope that are broken as failed", func(t *testing.T) {
+		cfg := dependencies.LoadEnv()
+
+		c := NewCrawler(cfg, fetcher.NewMockFetcher(), WithLinkChecker(statusFetcher{}))
+		c.Run(context.Background(), "https://monzo.com/")
+
+		for _, r := range c.Results().(*MemorySink).Results() {
+			if r.URL == "https://twitter.com/monzo" {
+				assert.Equal(t, PageFailed, r.Status)
+				assert.Equal(t, http.StatusNotFound, r.StatusCode)
+				return
+			}
+		}
+		t.Fatal("https://twitter.com/monzo wasn't checked")
+	})
+
+	t.Run("records the status code of pages that respond with an error", func(t *testing.T) {
+		testServer := httptest.NewServer(http.NotFoundHandler())
+		defer testServer.Close()
+
+		cfg := dependencies.LoadEnv()
+		c := NewCrawler(cfg, fetcher.NewFetcher())
+		c.Run(context.Background(), testServer.URL)
+
+		results := c.Results().(*MemorySink).Results()
+		require.Len(t, results, 1)
+		assert.Equal(t, PageFailed, results[0].Status)
+		assert.Equal(t, http.StatusNotFound, results[0].StatusCode)
+		assert.Positive(t, results[0].Latency)
+	})
+}
+
 func TestCrawler_RunUnbounded(t *testing.T) {
 	os.Setenv("APP_ENV", "test")
 	cfg := dependencies.LoadEnv()
@@ -18,16 +685,10 @@ func TestCrawler_RunUnbounded(t *testing.T) {
 	t.Run("when the starting URL has valid links", func(t *testing.T) {
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		c.RunUnbounded("https://monzo.com/", 1)
+		c.RunUnbounded(context.Background(), "https://monzo.com/")
 
-		require.NotEmpty(t, c.Visited)
-
-		i := 0
-		urls := make([]string, len(c.Visited))
-		for k := range c.Visited {
-			urls[i] = k
-			i++
-		}
+		urls := visitedUrls(c)
+		require.NotEmpty(t, urls)
 
 		assert.ElementsMatch(t, []string{
 			"https://monzo.com/",
@@ -42,10 +703,30 @@ func TestCrawler_RunUnbounded(t *testing.T) {
 	t.Run("when the starting URL has no valid links", func(t *testing.T) {
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		c.RunUnbounded("http://dummysite.com/", 1)
+		c.RunUnbounded(context.Background(), "http://dummysite.com/")
 
-		assert.Len(t, c.Visited, 1)
-		assert.True(t, c.Visited["http://dummysite.com/"])
+		assert.Equal(t, 1, c.Visited().Len())
+		assert.True(t, c.Visited().Has("http://dummysite.com/"))
+		assert.Equal(t, map[string]string{
+			"http://dummysite.com/": "permanent error: cannot parse any urls from: http://dummysite.com/",
+		}, c.Skipped)
+	})
+
+	t.Run("when robots.txt disallows some links", func(t *testing.T) {
+		f := fetcher.NewMockFetcher()
+		r := robots.NewMockRobots("https://monzo.com/current-account/")
+		c := NewCrawler(cfg, f, WithRobots(r))
+		c.RunUnbounded(context.Background(), "https://monzo.com/")
+
+		urls := visitedUrls(c)
+		require.NotEmpty(t, urls)
+
+		assert.ElementsMatch(t, []string{
+			"https://monzo.com/",
+			"https://monzo.com/current-account/",
+			"https://monzo.com/monzo-plus/",
+		}, urls)
+		assert.Equal(t, map[string]string{"https://monzo.com/current-account/": "blocked by robots"}, c.Skipped)
 	})
 
 	t.Run("when crawl depth is limited", func(t *testing.T) {
@@ -53,16 +734,10 @@ func TestCrawler_RunUnbounded(t *testing.T) {
 
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		c.RunUnbounded("https://monzo.com/", 1)
+		c.RunUnbounded(context.Background(), "https://monzo.com/")
 
-		require.NotEmpty(t, c.Visited)
-
-		i := 0
-		urls := make([]string, len(c.Visited))
-		for k := range c.Visited {
-			urls[i] = k
-			i++
-		}
+		urls := visitedUrls(c)
+		require.NotEmpty(t, urls)
 
 		assert.ElementsMatch(t, []string{
 			"https://monzo.com/",
@@ -79,16 +754,10 @@ func TestCrawler_RunBounded(t *testing.T) {
 	t.Run("when the starting URL has valid links", func(t *testing.T) {
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		c.RunBounded("https://monzo.com/", 1)
+		c.RunBounded(context.Background(), "https://monzo.com/")
 
-		require.NotEmpty(t, c.Visited)
-
-		i := 0
-		urls := make([]string, len(c.Visited))
-		for k := range c.Visited {
-			urls[i] = k
-			i++
-		}
+		urls := visitedUrls(c)
+		require.NotEmpty(t, urls)
 
 		assert.ElementsMatch(t, []string{
 			"https://monzo.com/",
@@ -103,10 +772,30 @@ func TestCrawler_RunBounded(t *testing.T) {
 	t.Run("when the starting URL has no valid links", func(t *testing.T) {
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		c.RunBounded("http://dummysite.com/", 1)
+		c.RunBounded(context.Background(), "http://dummysite.com/")
 
-		assert.Len(t, c.Visited, 1)
-		assert.True(t, c.Visited["http://dummysite.com/"])
+		assert.Equal(t, 1, c.Visited().Len())
+		assert.True(t, c.Visited().Has("http://dummysite.com/"))
+		assert.Equal(t, map[string]string{
+			"http://dummysite.com/": "permanent error: cannot parse any urls from: http://dummysite.com/",
+		}, c.Skipped)
+	})
+
+	t.Run("when robots.txt disallows some links", func(t *testing.T) {
+		f := fetcher.NewMockFetcher()
+		r := robots.NewMockRobots("https://monzo.com/current-account/")
+		c := NewCrawler(cfg, f, WithRobots(r))
+		c.RunBounded(context.Background(), "https://monzo.com/")
+
+		urls := visitedUrls(c)
+		require.NotEmpty(t, urls)
+
+		assert.ElementsMatch(t, []string{
+			"https://monzo.com/",
+			"https://monzo.com/current-account/",
+			"https://monzo.com/monzo-plus/",
+		}, urls)
+		assert.Equal(t, map[string]string{"https://monzo.com/current-account/": "blocked by robots"}, c.Skipped)
 	})
 
 	t.Run("when crawl depth is limited", func(t *testing.T) {
@@ -114,16 +803,10 @@ func TestCrawler_RunBounded(t *testing.T) {
 
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		c.RunBounded("https://monzo.com/", 1)
+		c.RunBounded(context.Background(), "https://monzo.com/")
 
-		require.NotEmpty(t, c.Visited)
-
-		i := 0
-		urls := make([]string, len(c.Visited))
-		for k := range c.Visited {
-			urls[i] = k
-			i++
-		}
+		urls := visitedUrls(c)
+		require.NotEmpty(t, urls)
 
 		assert.ElementsMatch(t, []string{
 			"https://monzo.com/",
@@ -133,12 +816,123 @@ func TestCrawler_RunBounded(t *testing.T) {
 	})
 
 	t.Run("respects the max concurrency limit", func(t *testing.T) {
+		cfg.MaxCrawlDepth = -1
+
+		f := &concurrencyFetcher{fanOut: 50, delay: 20 * time.Millisecond}
+		c := NewCrawler(cfg, f)
+
+		before := runtime.NumGoroutine()
+		c.RunBounded(context.Background(), "https://monzo.com/")
+
+		assert.Equal(t, 51, c.Visited().Len())
+		assert.Equal(t, int64(cfg.MaxCrawlConcurrencyLevel), f.maxActive.Load())
+
+		// Workers may take a moment to exit after signalling that they're done.
+		for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), before, "workers should not outlive the crawl")
+	})
+
+	t.Run("finishes as soon as there's nothing left to crawl", func(t *testing.T) {
+		f := fetcher.NewMockFetcher()
+		c := NewCrawler(cfg, f)
+
+		start := time.Now()
+		c.RunBounded(context.Background(), "https://monzo.com/")
+
+		assert.Equal(t, 6, c.Visited().Len())
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
+	})
+
+	t.Run("visits links in the frontier's order", func(t *testing.T) {
+		cfg.MaxCrawlConcurrencyLevel = 1
+		defer func() { cfg.MaxCrawlConcurrencyLevel = 5 }()
+
+		cases := map[string]struct {
+			frontier Frontier
+			expected []string
+		}{
+			"breadth-first": {NewFIFOFrontier(), []string{
+				"https://monzo.com/",
+				"https://monzo.com/current-account/",
+				"https://monzo.com/monzo-plus/",
+				"https://monzo.com/help/",
+				"https://monzo.com/current-account/joint-account/",
+				"https://monzo.com/switch/",
+			}},
+			"depth-first": {NewLIFOFrontier(), []string{
+				"https://monzo.com/",
+				"https://monzo.com/monzo-plus/",
+				"https://monzo.com/current-account/",
+				"https://monzo.com/switch/",
+				"https://monzo.com/current-account/joint-account/",
+				"https://monzo.com/help/",
+			}},
+		}
+
+		for name, tc := range cases {
+			t.Run(name, func(t *testing.T) {
+				f := &orderFetcher{fetcher: fetcher.NewMockFetcher()}
+				c := NewCrawler(cfg, f, WithFrontier(tc.frontier))
+				c.RunBounded(context.Background(), "https://monzo.com/")
+
+				assert.Equal(t, tc.expected, f.order)
+			})
+		}
+	})
+
+	t.Run("keeps track of visited links in the given store", func(t *testing.T) {
+		store, err := NewFileVisitedStore(t.TempDir())
+		require.NoError(t, err)
+		defer store.Close()
+
+		f := fetcher.NewMockFetcher()
+		c := NewCrawler(cfg, f, WithVisitedStore(store))
+		c.RunBounded(context.Background(), "https://monzo.com/")
+
+		assert.Equal(t, 6, store.Len())
+		assert.True(t, store.Has("https://monzo.com/current-account/joint-account/"))
+	})
+
+	t.Run("crawls everything with a probabilistic visited store", func(t *testing.T) {
+		store := NewBloomVisitedStore(100, 0.001)
+
+		f := fetcher.NewMockFetcher()
+		c := NewCrawler(cfg, f, WithVisitedStore(store))
+		c.RunBounded(context.Background(), "https://monzo.com/")
+
+		assert.Equal(t, 6, store.Len())
+		assert.True(t, store.Has("https://monzo.com/current-account/joint-account/"))
+	})
+
+	t.Run("only counts what it doesn't crawl in low memory mode", func(t *testing.T) {
+		full := NewCrawler(cfg, fetcher.NewMockFetcher())
+		full.RunBounded(context.Background(), "https://monzo.com/")
+
+		sink := NewCountingSink()
+		c := NewCrawler(cfg, fetcher.NewMockFetcher(), WithVisitedStore(NewBloomVisitedStore(100, 0.001)), WithLowMemory(NewBloomVisitedStore(100, 0.001)), WithResultSink(sink))
+		c.RunBounded(context.Background(), "https://monzo.com/")
+
+		counts := make(map[PageStatus]int)
+		for _, r := range full.Results().(*MemorySink).Results() {
+			counts[r.Status]++
+		}
+		assert.Equal(t, counts, sink.Counts())
+		assert.Equal(t, 1, sink.Count(PageOutOfScope))
+		assert.Empty(t, c.Skipped)
+		assert.NotZero(t, c.SkippedLen())
+		assert.Equal(t, len(full.Skipped), c.SkippedLen())
+	})
+
+	t.Run("finishes straight away when there's nothing to crawl", func(t *testing.T) {
 		f := fetcher.NewMockFetcher()
 		c := NewCrawler(cfg, f)
-		go c.RunBounded("https://monzo.com/", 1)
 
-		time.Sleep(1 * time.Second)
+		start := time.Now()
+		c.RunBounded(context.Background())
 
-		require.Equal(t, cfg.MaxCrawlConcurrencyLevel+5, runtime.NumGoroutine()) // guesstimate: +5 is the sweet spot reserved for non-worker goroutines operation in this function call.
+		assert.Zero(t, c.Visited().Len())
+		assert.Less(t, time.Since(start), 100*time.Millisecond)
 	})
 }
@@ -0,0 +1,262 @@
+package crawler
+
+import (
+	"container/heap"
+	"fmt"
+	"regexp"
+	"sort"
+	"webcrawler-go/internal/fetcher"
+)
+
+// FrontierItem is a link waiting to be crawled.
+type FrontierItem struct {
+	URL    string `json:"url"`
+	Depth  int    `json:"depth"`
+	Parent string `json:"parent,omitempty"` // The page the link was found on. Seeds have none.
+
+	Kind fetcher.LinkKind `json:"kind,omitempty"` // What the link is used for on Parent, e.g. an image. Seeds have none.
+
+	CheckOnly bool `json:"checkOnly,omitempty"` // Only check that the link works, without crawling it. See WithLinkChecker and WithFollowKinds.
+}
+
+// Frontier decides the order in which pending links get crawled. Implementations aren't safe for concurrent use, as
+// RunBounded's main worker is the only one that touches the frontier.
+type Frontier interface {
+	Push(item *FrontierItem)
+	Pop() (*FrontierItem, bool)
+	Len() int
+	Items() []*FrontierItem // Returns the pending links in the order they need to be pushed in to rebuild the frontier.
+}
+
+const (
+	StrategyBFS      = "bfs"
+	StrategyDFS      = "dfs"
+	StrategyPriority = "priority"
+
+	PriorityByDepth   = "depth"
+	PriorityByInlinks = "inlinks"
+	PriorityByPattern = "pattern"
+)
+
+// NewFrontier builds the frontier for the given strategy. The priority and patterns are only used by the priority strategy.
+func NewFrontier(strategy string, priority string, patterns []string) (Frontier, error) {
+	switch strategy {
+	case StrategyBFS, "":
+		return NewFIFOFrontier(), nil
+	case StrategyDFS:
+		return NewLIFOFrontier(), nil
+	case StrategyPriority:
+		scorer, err := newScorer(priority, patterns)
+		if err != nil {
+			return nil, err
+		}
+		return NewPriorityFrontier(scorer), nil
+	default:
+		return nil, fmt.Errorf("unknown crawl strategy %q", strategy)
+	}
+}
+
+// FIFOFrontier crawls links in the order they were found, i.e. breadth-first.
+type FIFOFrontier struct {
+	items []*FrontierItem
+	head  int
+}
+
+func NewFIFOFrontier() *FIFOFrontier {
+	return &FIFOFrontier{}
+}
+
+func (f *FIFOFrontier) Push(item *FrontierItem) {
+	f.items = append(f.items, item)
+}
+
+func (f *FIFOFrontier) Pop() (*FrontierItem, bool) {
+	if f.head >= len(f.items) {
+		return nil, false
+	}
+
+	item := f.items[f.head]
+	f.items[f.head] = nil
+	f.head++
+
+	// Reclaim the popped half of the slice once it's worth the copy.
+	if f.head > 1024 && f.head*2 >= len(f.items) {
+		f.items = append([]*FrontierItem(nil), f.items[f.head:]...)
+		f.head = 0
+	}
+
+	return item, true
+}
+
+func (f *FIFOFrontier) Len() int {
+	return len(f.items) - f.head
+}
+
+func (f *FIFOFrontier) Items() []*FrontierItem {
+	return append([]*FrontierItem(nil), f.items[f.head:]...)
+}
+
+// LIFOFrontier crawls the most recently found links first, i.e. depth-first.
+type LIFOFrontier struct {
+	items []*FrontierItem
+}
+
+func NewLIFOFrontier() *LIFOFrontier {
+	return &LIFOFrontier{}
+}
+
+func (f *LIFOFrontier) Push(item *FrontierItem) {
+	f.items = append(f.items, item)
+}
+
+func (f *LIFOFrontier) Pop() (*FrontierItem, bool) {
+	if len(f.items) == 0 {
+		return nil, false
+	}
+
+	item := f.items[len(f.items)-1]
+	f.items[len(f.items)-1] = nil
+	f.items = f.items[:len(f.items)-1]
+
+	return item, true
+}
+
+func (f *LIFOFrontier) Len() int {
+	return len(f.items)
+}
+
+func (f *LIFOFrontier) Items() []*FrontierItem {
+	return append([]*FrontierItem(nil), f.items...)
+}
+
+// Scorer rates how important a link is to crawl. Higher scores are crawled first.
+type Scorer func(item *FrontierItem) float64
+
+// PriorityFrontier crawls the highest scoring links first. Links with the same score are crawled in the order they were found.
+// Each link is scored once, when it's pushed.
+type PriorityFrontier struct {
+	scorer Scorer
+	items  priorityQueue
+	seq    int
+}
+
+func NewPriorityFrontier(scorer Scorer) *PriorityFrontier {
+	return &PriorityFrontier{scorer: scorer}
+}
+
+func (f *PriorityFrontier) Push(item *FrontierItem) {
+	heap.Push(&f.items, &scoredItem{item: item, score: f.scorer(item), seq: f.seq})
+	f.seq++
+}
+
+func (f *PriorityFrontier) Pop() (*FrontierItem, bool) {
+	if len(f.items) == 0 {
+		return nil, false
+	}
+	return heap.Pop(&f.items).(*scoredItem).item, true
+}
+
+func (f *PriorityFrontier) Len() int {
+	return len(f.items)
+}
+
+// Items returns the pending links in the order they were pushed, as they're scored again when pushed back in.
+func (f *PriorityFrontier) Items() []*FrontierItem {
+	scored := append(priorityQueue(nil), f.items...)
+	sort.Slice(scored, func(i, j int) bool {
+		return scored[i].seq < scored[j].seq
+	})
+
+	items := make([]*FrontierItem, len(scored))
+	for i, s := range scored {
+		items[i] = s.item
+	}
+	return items
+}
+
+// ScoreByDepth favours links that are closer to the seeds.
+func ScoreByDepth(item *FrontierItem) float64 {
+	return -float64(item.Depth)
+}
+
+// NewInlinkScorer favours links that have been found on the most pages so far. As the scorer runs on every push, a link
+// that keeps being found is pushed again with a higher score, and whichever copy is popped first gets crawled.
+func NewInlinkScorer() Scorer {
+	inlinks := make(map[string]int)
+	return func(item *FrontierItem) float64 {
+		inlinks[item.URL]++
+		return float64(inlinks[item.URL])
+	}
+}
+
+// NewPatternScorer favours links matching the given regexes, with earlier patterns ranking higher than later ones.
+// Links that match no pattern are crawled last, breadth-first.
+func NewPatternScorer(patterns []string) (Scorer, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid priority pattern %q: %w", p, err)
+		}
+		res[i] = re
+	}
+
+	return func(item *FrontierItem) float64 {
+		for i, re := range res {
+			if re.MatchString(item.URL) {
+				return float64(len(res) - i)
+			}
+		}
+		return 0
+	}, nil
+}
+
+func newScorer(priority string, patterns []string) (Scorer, error) {
+	switch priority {
+	case PriorityByDepth, "":
+		return ScoreByDepth, nil
+	case PriorityByInlinks:
+		return NewInlinkScorer(), nil
+	case PriorityByPattern:
+		return NewPatternScorer(patterns)
+	default:
+		return nil, fmt.Errorf("unknown crawl priority %q", priority)
+	}
+}
+
+type scoredItem struct {
+	item  *FrontierItem
+	score float64
+	seq   int
+}
+
+// priorityQueue implements heap.Interface as a max-heap on score, breaking ties by insertion order.
+type priorityQueue []*scoredItem
+
+func (q priorityQueue) Len() int {
+	return len(q)
+}
+
+func (q priorityQueue) Less(i, j int) bool {
+	if q[i].score != q[j].score {
+		return q[i].score > q[j].score
+	}
+	return q[i].seq < q[j].seq
+}
+
+func (q priorityQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+}
+
+func (q *priorityQueue) Push(x any) {
+	*q = append(*q, x.(*scoredItem))
+}
+
+func (q *priorityQueue) Pop() any {
+	old := *q
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*q = old[:n-1]
+	return item
+}
@@ -0,0 +1,151 @@
+package crawler
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func drain(f Frontier) []string {
+	var urls []string
+	for f.Len() > 0 {
+		item, ok := f.Pop()
+		if !ok {
+			break
+		}
+		urls = append(urls, item.URL)
+	}
+	return urls
+}
+
+func pushAll(f Frontier, items ...*FrontierItem) {
+	for _, item := range items {
+		f.Push(item)
+	}
+}
+
+func TestFrontier(t *testing.T) {
+	items := func() []*FrontierItem {
+		return []*FrontierItem{
+			{URL: "https://monzo.com/a", Depth: 3},
+			{URL: "https://monzo.com/blog/b", Depth: 1},
+			{URL: "https://monzo.com/c", Depth: 2},
+			{URL: "https://monzo.com/help/d", Depth: 1},
+		}
+	}
+
+	t.Run("FIFO pops links in the order they were pushed", func(t *testing.T) {
+		f := NewFIFOFrontier()
+		pushAll(f, items()...)
+
+		assert.Equal(t, 4, f.Len())
+		assert.Equal(t, []string{"https://monzo.com/a", "https://monzo.com/blog/b", "https://monzo.com/c", "https://monzo.com/help/d"}, drain(f))
+		assert.Equal(t, 0, f.Len())
+
+		_, ok := f.Pop()
+		assert.False(t, ok)
+	})
+
+	t.Run("FIFO keeps its order while compacting", func(t *testing.T) {
+		f := NewFIFOFrontier()
+		for i := 0; i < 5000; i++ {
+			f.Push(&FrontierItem{Depth: i})
+			if i%2 == 0 {
+				item, ok := f.Pop()
+				require.True(t, ok)
+				require.Equal(t, i/2, item.Depth)
+			}
+		}
+		assert.Equal(t, 2500, f.Len())
+
+		item, ok := f.Pop()
+		require.True(t, ok)
+		assert.Equal(t, 2500, item.Depth)
+	})
+
+	t.Run("LIFO pops the most recently pushed links first", func(t *testing.T) {
+		f := NewLIFOFrontier()
+		pushAll(f, items()...)
+
+		assert.Equal(t, 4, f.Len())
+		assert.Equal(t, []string{"https://monzo.com/help/d", "https://monzo.com/c", "https://monzo.com/blog/b", "https://monzo.com/a"}, drain(f))
+
+		_, ok := f.Pop()
+		assert.False(t, ok)
+	})
+
+	t.Run("priority by depth pops the shallowest links first", func(t *testing.T) {
+		f := NewPriorityFrontier(ScoreByDepth)
+		pushAll(f, items()...)
+
+		assert.Equal(t, []string{"https://monzo.com/blog/b", "https://monzo.com/help/d", "https://monzo.com/c", "https://monzo.com/a"}, drain(f))
+	})
+
+	t.Run("priority by pattern pops links matching the earliest pattern first", func(t *testing.T) {
+		scorer, err := NewPatternScorer([]string{"/help/", "/blog/"})
+		require.NoError(t, err)
+
+		f := NewPriorityFrontier(scorer)
+		pushAll(f, items()...)
+
+		assert.Equal(t, []string{"https://monzo.com/help/d", "https://monzo.com/blog/b", "https://monzo.com/a", "https://monzo.com/c"}, drain(f))
+	})
+
+	t.Run("priority by inlinks pops the most linked-to links first", func(t *testing.T) {
+		f := NewPriorityFrontier(NewInlinkScorer())
+		pushAll(f, items()...)
+		pushAll(f, &FrontierItem{URL: "https://monzo.com/c"}, &FrontierItem{URL: "https://monzo.com/c"}, &FrontierItem{URL: "https://monzo.com/a"})
+
+		popped := drain(f)
+		assert.Equal(t, []string{"https://monzo.com/c", "https://monzo.com/c", "https://monzo.com/a"}, popped[:3])
+		assert.Len(t, popped, 7)
+	})
+
+	t.Run("lists pending links in an order that rebuilds the frontier", func(t *testing.T) {
+		for name, newFrontier := range map[string]func() Frontier{
+			"FIFO":     func() Frontier { return NewFIFOFrontier() },
+			"LIFO":     func() Frontier { return NewLIFOFrontier() },
+			"priority": func() Frontier { return NewPriorityFrontier(ScoreByDepth) },
+		} {
+			t.Run(name, func(t *testing.T) {
+				f := newFrontier()
+				pushAll(f, items()...)
+				f.Pop()
+
+				rebuilt := newFrontier()
+				pushAll(rebuilt, f.Items()...)
+
+				assert.Equal(t, drain(f), drain(rebuilt))
+			})
+		}
+	})
+}
+
+func TestNewFrontier(t *testing.T) {
+	t.Run("builds each strategy", func(t *testing.T) {
+		f, err := NewFrontier(StrategyBFS, "", nil)
+		require.NoError(t, err)
+		assert.IsType(t, &FIFOFrontier{}, f)
+
+		f, err = NewFrontier(StrategyDFS, "", nil)
+		require.NoError(t, err)
+		assert.IsType(t, &LIFOFrontier{}, f)
+
+		for _, priority := range []string{PriorityByDepth, PriorityByInlinks, PriorityByPattern} {
+			f, err = NewFrontier(StrategyPriority, priority, []string{"/blog/"})
+			require.NoError(t, err)
+			assert.IsType(t, &PriorityFrontier{}, f)
+		}
+	})
+
+	t.Run("rejects unknown settings", func(t *testing.T) {
+		_, err := NewFrontier("random", "", nil)
+		assert.ErrorContains(t, err, `unknown crawl strategy "random"`)
+
+		_, err = NewFrontier(StrategyPriority, "pagerank", nil)
+		assert.ErrorContains(t, err, `unknown crawl priority "pagerank"`)
+
+		_, err = NewFrontier(StrategyPriority, PriorityByPattern, []string{"(unclosed"})
+		assert.ErrorContains(t, err, `invalid priority pattern "(unclosed"`)
+	})
+}
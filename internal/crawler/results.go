@@ -0,0 +1,128 @@
+package crawler
+
+import (
+	"sync"
+	"time"
+	"webcrawler-go/internal/fetcher"
+)
+
+type PageStatus string
+
+const (
+	PageSucceeded    PageStatus = "succeeded"
+	PageFailed       PageStatus = "failed"
+	PageSkippedDepth PageStatus = "skipped-depth"    // The page was past MAX_CRAWL_DEPTH.
+	PageOutOfScope   PageStatus = "out-of-scope"     // The page was linked to, but it's outside the crawl's scope.
+	PageBlocked      PageStatus = "blocked"          // robots.txt disallows crawling the page.
+	PageStopped      PageStatus = "stopped"          // The crawl stopped before the page could be fetched.
+	PageQuarantined  PageStatus = "quarantined"      // The page looked like part of a crawler trap. See TrapDetector.
+	PageChecked      PageStatus = "checked"          // The page was checked to work without being crawled, as it's outside the crawl's scope or an asset.
+	PageNoFollow     PageStatus = "skipped-nofollow" // The page was only linked to by links that asked not to be followed. See WithRespectNoFollow.
+)
+
+// PageResult records what happened to a single URL that the crawler came across.
+type PageResult struct {
+	URL         string           `json:"url"`
+	Status      PageStatus       `json:"status"`
+	StatusCode  int              `json:"statusCode,omitempty"`
+	Depth       int              `json:"depth"`
+	Parent      string           `json:"parent,omitempty"` // The page the URL was first found on. Seeds have none.
+	Kind        fetcher.LinkKind `json:"kind,omitempty"`   // What the URL is used for on Parent, e.g. an image. Seeds have none.
+	Latency     time.Duration    `json:"latency,omitempty"`
+	ContentType string           `json:"contentType,omitempty"`
+	Size        int64            `json:"size,omitempty"`
+	Error       string           `json:"error,omitempty"` // Why the page failed or was skipped.
+
+	LastModified *time.Time `json:"lastModified,omitempty"` // From the page's Last-Modified header. Nil if unknown.
+	Canonical    string     `json:"canonical,omitempty"`    // The page's canonical URL, if it declares one.
+	NoIndex      bool       `json:"noIndex,omitempty"`      // Whether the page asked to be kept out of search indexes.
+	NoFollow     bool       `json:"noFollow,omitempty"`     // Whether the page asked for its links not to be followed.
+	Anchors      []string   `json:"anchors,omitempty"`      // The ids and <a name>s on the page that fragments can point to.
+
+	FinalURL  string             `json:"finalUrl,omitempty"`  // Where the page's redirects led to, if it had any.
+	Redirects []fetcher.Redirect `json:"redirects,omitempty"` // The redirects that were followed to get to FinalURL.
+}
+
+// ResultSink collects the result of every URL that the crawler comes across. It must be safe for concurrent use.
+type ResultSink interface {
+	Record(r *PageResult)
+}
+
+// MemorySink keeps every result in memory, in the order they were recorded.
+type MemorySink struct {
+	results []*PageResult
+	lock    sync.Mutex
+}
+
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (s *MemorySink) Record(r *PageResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.results = append(s.results, r)
+}
+
+func (s *MemorySink) Results() []*PageResult {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return append([]*PageResult(nil), s.results...)
+}
+
+func (s *MemorySink) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return len(s.results)
+}
+
+// CountingSink only counts results by status, for crawls too large to keep every result in memory.
+type CountingSink struct {
+	counts map[PageStatus]int
+	lock   sync.Mutex
+}
+
+func NewCountingSink() *CountingSink {
+	return &CountingSink{counts: make(map[PageStatus]int)}
+}
+
+func (s *CountingSink) Record(r *PageResult) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.counts[r.Status]++
+}
+
+// Count returns the no. of results with the given status.
+func (s *CountingSink) Count(status PageStatus) int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.counts[status]
+}
+
+// Counts returns the no. of results per status.
+func (s *CountingSink) Counts() map[PageStatus]int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	counts := make(map[PageStatus]int, len(s.counts))
+	for status, n := range s.counts {
+		counts[status] = n
+	}
+	return counts
+}
+
+func (s *CountingSink) Len() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	n := 0
+	for _, count := range s.counts {
+		n += count
+	}
+	return n
+}
@@ -0,0 +1,190 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Scope decides which of the links found on a page the crawler should follow. By default, only links to the same site as the
+// page they were found on are followed, ignoring any "www." prefix.
+type Scope struct {
+	schemes  []string
+	hosts    []string
+	prefixes []string
+	include  []*regexp.Regexp
+	exclude  []*regexp.Regexp
+
+	includePatterns []string
+	excludePatterns []string
+}
+
+var defaultSchemes = []string{"http", "https"}
+
+type ScopeOption func(s *Scope)
+
+// WithSchemes limits links to the given schemes, e.g. only "https". By default, both http and https links are followed.
+func WithSchemes(schemes ...string) ScopeOption {
+	return func(s *Scope) {
+		s.schemes = schemes
+	}
+}
+
+// WithHosts limits links to the given hosts rather than the site of the page they were found on. "*.monzo.com" allows
+// monzo.com and any of its subdomains. A "www." prefix is ignored either way.
+func WithHosts(hosts ...string) ScopeOption {
+	return func(s *Scope) {
+		s.hosts = hosts
+	}
+}
+
+// WithPathPrefixes limits links to the ones whose path starts with any of the prefixes, e.g. "/docs/".
+func WithPathPrefixes(prefixes ...string) ScopeOption {
+	return func(s *Scope) {
+		s.prefixes = prefixes
+	}
+}
+
+// WithIncludePatterns limits links to the ones that match any of the regexes.
+func WithIncludePatterns(patterns ...string) ScopeOption {
+	return func(s *Scope) {
+		s.includePatterns = patterns
+	}
+}
+
+// WithExcludePatterns drops any links that match any of the regexes.
+func WithExcludePatterns(patterns ...string) ScopeOption {
+	return func(s *Scope) {
+		s.excludePatterns = patterns
+	}
+}
+
+func NewScope(opts ...ScopeOption) (*Scope, error) {
+	s := &Scope{
+		schemes: defaultSchemes,
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var err error
+	if s.include, err = compilePatterns("include", s.includePatterns); err != nil {
+		return nil, err
+	}
+	if s.exclude, err = compilePatterns("exclude", s.excludePatterns); err != nil {
+		return nil, err
+	}
+
+	hosts := make([]string, len(s.hosts))
+	for i, h := range s.hosts {
+		hosts[i] = strings.TrimPrefix(strings.ToLower(h), "www.")
+	}
+	s.hosts = hosts
+
+	return s, nil
+}
+
+func compilePatterns(kind string, patterns []string) ([]*regexp.Regexp, error) {
+	res := make([]*regexp.Regexp, len(patterns))
+	for i, p := range patterns {
+		re, err := regexp.Compile(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s pattern %q: %w", kind, p, err)
+		}
+		res[i] = re
+	}
+	return res, nil
+}
+
+// Check decides whether a link found on the from page is in scope. If it isn't, it returns the rule that rejected it.
+func (s *Scope) Check(from, link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return fmt.Sprintf("unparseable url: %v", err), false
+	}
+
+	if !contains(s.schemes, u.Scheme) {
+		return fmt.Sprintf("scheme: %s isn't one of %s", u.Scheme, strings.Join(s.schemes, ", ")), false
+	}
+
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	if len(s.hosts) == 0 {
+		if !sameSite(from, link) {
+			return fmt.Sprintf("host: %s isn't on the same site as %s", u.Hostname(), from), false
+		}
+	} else if !s.allowedHost(host) {
+		return fmt.Sprintf("host: %s isn't one of %s", u.Hostname(), strings.Join(s.hosts, ", ")), false
+	}
+
+	if len(s.prefixes) > 0 && !hasAnyPrefix(u.Path, s.prefixes) {
+		return fmt.Sprintf("path prefix: %s isn't under %s", u.Path, strings.Join(s.prefixes, ", ")), false
+	}
+
+	for i, re := range s.exclude {
+		if re.MatchString(link) {
+			return fmt.Sprintf("exclude: matches %s", s.excludePatterns[i]), false
+		}
+	}
+
+	if len(s.include) > 0 && !matchesAny(s.include, link) {
+		return fmt.Sprintf("include: doesn't match any of %s", strings.Join(s.includePatterns, ", ")), false
+	}
+
+	return "", true
+}
+
+func (s *Scope) allowedHost(host string) bool {
+	for _, h := range s.hosts {
+		if base, ok := strings.CutPrefix(h, "*."); ok {
+			if host == base || strings.HasSuffix(host, "."+base) {
+				return true
+			}
+		} else if host == h {
+			return true
+		}
+	}
+	return false
+}
+
+// sameSite reports whether both URLs are on the same host, ignoring any "www." prefix.
+func sameSite(a, b string) bool {
+	ua, err := url.Parse(a)
+	if err != nil {
+		return false
+	}
+	ub, err := url.Parse(b)
+	if err != nil {
+		return false
+	}
+
+	return strings.TrimPrefix(strings.ToLower(ua.Hostname()), "www.") == strings.TrimPrefix(strings.ToLower(ub.Hostname()), "www.")
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesAny(res []*regexp.Regexp, s string) bool {
+	for _, re := range res {
+		if re.MatchString(s) {
+			return true
+		}
+	}
+	return false
+}
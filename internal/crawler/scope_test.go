@@ -0,0 +1,135 @@
+package crawler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScope_Check(t *testing.T) {
+	const from = "https://monzo.com/"
+
+	tests := []struct {
+		name   string
+		opts   []ScopeOption
+		link   string
+		inRule string // The rule that rejects the link, or empty if it's in scope.
+	}{
+		{
+			name: "follows links on the same site by default",
+			link: "https://monzo.com/help/",
+		},
+		{
+			name: "ignores www. when comparing sites",
+			link: "https://www.monzo.com/help/",
+		},
+		{
+			name:   "rejects links to other sites by default",
+			link:   "https://community.monzo.com/",
+			inRule: "host: community.monzo.com isn't on the same site as https://monzo.com/",
+		},
+		{
+			name:   "rejects links with other schemes",
+			link:   "ftp://monzo.com/file",
+			inRule: "scheme: ftp isn't one of http, https",
+		},
+		{
+			name:   "rejects http links when only https is allowed",
+			opts:   []ScopeOption{WithSchemes("https")},
+			link:   "http://monzo.com/",
+			inRule: "scheme: http isn't one of https",
+		},
+		{
+			name: "follows links to any of the listed hosts",
+			opts: []ScopeOption{WithHosts("monzo.com", "WWW.Monzo.Me")},
+			link: "https://monzo.me/someone",
+		},
+		{
+			name:   "rejects links to hosts that aren't listed",
+			opts:   []ScopeOption{WithHosts("monzo.com")},
+			link:   "https://community.monzo.com/",
+			inRule: "host: community.monzo.com isn't one of monzo.com",
+		},
+		{
+			name: "follows links to subdomains of a wildcard host",
+			opts: []ScopeOption{WithHosts("*.monzo.com")},
+			link: "https://community.monzo.com/",
+		},
+		{
+			name: "follows links to the apex of a wildcard host",
+			opts: []ScopeOption{WithHosts("*.monzo.com")},
+			link: "https://monzo.com/",
+		},
+		{
+			name:   "doesn't take a wildcard host as a suffix of any host",
+			opts:   []ScopeOption{WithHosts("*.monzo.com")},
+			link:   "https://notmonzo.com/",
+			inRule: "host: notmonzo.com isn't one of *.monzo.com",
+		},
+		{
+			name: "follows links under a path prefix",
+			opts: []ScopeOption{WithPathPrefixes("/blog/", "/help/")},
+			link: "https://monzo.com/help/cards",
+		},
+		{
+			name:   "rejects links outside the path prefixes",
+			opts:   []ScopeOption{WithPathPrefixes("/blog/")},
+			link:   "https://monzo.com/help/",
+			inRule: "path prefix: /help/ isn't under /blog/",
+		},
+		{
+			name:   "rejects links that match an exclude pattern",
+			opts:   []ScopeOption{WithExcludePatterns(`\.pdf$`, `[?&]sort=`)},
+			link:   "https://monzo.com/blog/?sort=new",
+			inRule: "exclude: matches [?&]sort=",
+		},
+		{
+			name: "follows links that match an include pattern",
+			opts: []ScopeOption{WithIncludePatterns("/blog/", "/help/")},
+			link: "https://monzo.com/help/",
+		},
+		{
+			name:   "rejects links that match no include pattern",
+			opts:   []ScopeOption{WithIncludePatterns("/blog/")},
+			link:   "https://monzo.com/help/",
+			inRule: "include: doesn't match any of /blog/",
+		},
+		{
+			name:   "applies exclude patterns before include patterns",
+			opts:   []ScopeOption{WithIncludePatterns("/blog/"), WithExcludePatterns("/blog/drafts/")},
+			link:   "https://monzo.com/blog/drafts/post",
+			inRule: "exclude: matches /blog/drafts/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewScope(tt.opts...)
+			require.NoError(t, err)
+
+			rule, ok := s.Check(from, tt.link)
+			assert.Equal(t, tt.inRule == "", ok)
+			assert.Equal(t, tt.inRule, rule)
+		})
+	}
+}
+
+func TestNewScope(t *testing.T) {
+	t.Run("returns an error for an invalid include pattern", func(t *testing.T) {
+		_, err := NewScope(WithIncludePatterns("/blog/", "(unclosed"))
+		assert.ErrorContains(t, err, `invalid include pattern "(unclosed"`)
+	})
+
+	t.Run("returns an error for an invalid exclude pattern", func(t *testing.T) {
+		_, err := NewScope(WithExcludePatterns("[z-a]"))
+		assert.ErrorContains(t, err, `invalid exclude pattern "[z-a]"`)
+	})
+
+	t.Run("doesn't modify the hosts it's given", func(t *testing.T) {
+		hosts := []string{"WWW.monzo.com"}
+		_, err := NewScope(WithHosts(hosts...))
+		require.NoError(t, err)
+		assert.Equal(t, []string{"WWW.monzo.com"}, hosts)
+	})
+}
@@ -0,0 +1,121 @@
+package crawler
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// TrapDetector quarantines links that look like they're part of an infinite URL space, e.g. calendars, faceted search or
+// relative links that keep getting deeper, so that a crawl without a MAX_CRAWL_DEPTH still comes to an end.
+// Every limit is disabled when it's 0.
+type TrapDetector struct {
+	maxSegments      int
+	maxRepeats       int
+	maxQueryVariants int
+	maxPerPattern    int
+
+	queryVariants map[string]int // The no. of URLs with a query string per host and path.
+	patterns      map[string]int // The no. of URLs per host and path pattern.
+	lock          sync.Mutex
+}
+
+// reasonTrap starts the reason of every quarantined link.
+const reasonTrap = "trap: "
+
+type TrapOption func(d *TrapDetector)
+
+// WithMaxPathSegments quarantines links whose path has more than n segments, e.g. /a/b/c has 3.
+func WithMaxPathSegments(n int) TrapOption {
+	return func(d *TrapDetector) {
+		d.maxSegments = n
+	}
+}
+
+// WithMaxRepeatedSegments quarantines links whose path has the same segment more than n times, e.g. /a/b/a/b/a/b.
+func WithMaxRepeatedSegments(n int) TrapOption {
+	return func(d *TrapDetector) {
+		d.maxRepeats = n
+	}
+}
+
+// WithMaxQueryVariants quarantines links to a path once n links to it with different query strings have been crawled.
+func WithMaxQueryVariants(n int) TrapOption {
+	return func(d *TrapDetector) {
+		d.maxQueryVariants = n
+	}
+}
+
+// WithMaxUrlsPerPattern quarantines links once n links with the same path pattern have been crawled. Numbers in the
+// path are ignored when working out its pattern, e.g. /calendar/2024/01/ and /calendar/2025/12/ have the same one.
+func WithMaxUrlsPerPattern(n int) TrapOption {
+	return func(d *TrapDetector) {
+		d.maxPerPattern = n
+	}
+}
+
+func NewTrapDetector(opts ...TrapOption) *TrapDetector {
+	d := &TrapDetector{
+		queryVariants: make(map[string]int),
+		patterns:      make(map[string]int),
+	}
+
+	for _, opt := range opts {
+		opt(d)
+	}
+
+	return d
+}
+
+// Check decides whether a link is safe to crawl. If it isn't, it returns the heuristic that quarantined it. Links that are
+// safe count towards the limits of later links, so every link should only be checked once.
+func (d *TrapDetector) Check(link string) (string, bool) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return fmt.Sprintf("unparseable url: %v", err), false
+	}
+
+	segments := pathSegments(u.Path)
+	if d.maxSegments > 0 && len(segments) > d.maxSegments {
+		return reasonTrap + fmt.Sprintf("path has %d segments, more than %d", len(segments), d.maxSegments), false
+	}
+
+	if d.maxRepeats > 0 {
+		repeats := make(map[string]int)
+		for _, s := range segments {
+			repeats[s]++
+			if repeats[s] > d.maxRepeats {
+				return reasonTrap + fmt.Sprintf("path segment %q appears more than %d times", s, d.maxRepeats), false
+			}
+		}
+	}
+
+	path := u.Host + u.Path
+	pattern := u.Host + numbers.ReplaceAllString(u.Path, "{n}")
+
+	d.lock.Lock()
+	defer d.lock.Unlock()
+
+	if d.maxQueryVariants > 0 && u.RawQuery != "" && d.queryVariants[path] >= d.maxQueryVariants {
+		return reasonTrap + fmt.Sprintf("more than %d query strings for %s", d.maxQueryVariants, path), false
+	}
+	if d.maxPerPattern > 0 && d.patterns[pattern] >= d.maxPerPattern {
+		return reasonTrap + fmt.Sprintf("more than %d urls like %s", d.maxPerPattern, pattern), false
+	}
+
+	if u.RawQuery != "" {
+		d.queryVariants[path]++
+	}
+	d.patterns[pattern]++
+
+	return "", true
+}
+
+var numbers = regexp.MustCompile(`[0-9]+`)
+
+// pathSegments returns the non-empty segments of a path.
+func pathSegments(path string) []string {
+	return strings.FieldsFunc(path, func(r rune) bool { return r == '/' })
+}
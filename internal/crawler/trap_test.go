@@ -0,0 +1,86 @@
+package crawler
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTrapDetector_Check(t *testing.T) {
+	t.Run("doesn't quarantine anything by default", func(t *testing.T) {
+		d := NewTrapDetector()
+		for i := 0; i < 100; i++ {
+			_, ok := d.Check(fmt.Sprintf("https://monzo.com/a/a/a/a/a/a/%d?page=%d", i, i))
+			assert.True(t, ok)
+		}
+	})
+
+	t.Run("quarantines paths with too many segments", func(t *testing.T) {
+		d := NewTrapDetector(WithMaxPathSegments(3))
+
+		_, ok := d.Check("https://monzo.com/a/b/c/")
+		assert.True(t, ok)
+
+		reason, ok := d.Check("https://monzo.com/a/b/c/d")
+		assert.False(t, ok)
+		assert.Equal(t, "trap: path has 4 segments, more than 3", reason)
+	})
+
+	t.Run("quarantines paths with repeated segments", func(t *testing.T) {
+		d := NewTrapDetector(WithMaxRepeatedSegments(2))
+
+		_, ok := d.Check("https://monzo.com/a/b/a/b/")
+		assert.True(t, ok)
+
+		reason, ok := d.Check("https://monzo.com/a/b/a/b/a/b/")
+		assert.False(t, ok)
+		assert.Equal(t, `trap: path segment "a" appears more than 2 times`, reason)
+	})
+
+	t.Run("caps the no. of query strings per path", func(t *testing.T) {
+		d := NewTrapDetector(WithMaxQueryVariants(2))
+
+		for _, u := range []string{
+			"https://monzo.com/search",
+			"https://monzo.com/search?q=a",
+			"https://monzo.com/search?q=b",
+			"https://monzo.com/other?q=c",
+		} {
+			_, ok := d.Check(u)
+			assert.True(t, ok, u)
+		}
+
+		reason, ok := d.Check("https://monzo.com/search?q=c")
+		assert.False(t, ok)
+		assert.Equal(t, "trap: more than 2 query strings for monzo.com/search", reason)
+	})
+
+	t.Run("caps the no. of urls per path pattern", func(t *testing.T) {
+		d := NewTrapDetector(WithMaxUrlsPerPattern(2))
+
+		for _, u := range []string{
+			"https://monzo.com/calendar/2024/01/",
+			"https://monzo.com/calendar/2024/02/",
+			"https://monzo.com/calendar/2024/",
+			"https://community.monzo.com/calendar/2024/03/",
+		} {
+			_, ok := d.Check(u)
+			assert.True(t, ok, u)
+		}
+
+		reason, ok := d.Check("https://monzo.com/calendar/2031/12/")
+		assert.False(t, ok)
+		assert.Equal(t, "trap: more than 2 urls like monzo.com/calendar/{n}/{n}/", reason)
+	})
+
+	t.Run("doesn't count quarantined links towards the limits", func(t *testing.T) {
+		d := NewTrapDetector(WithMaxPathSegments(2), WithMaxUrlsPerPattern(1))
+
+		_, ok := d.Check("https://monzo.com/a/1/b")
+		assert.False(t, ok)
+
+		_, ok = d.Check("https://monzo.com/a/2")
+		assert.True(t, ok)
+	})
+}
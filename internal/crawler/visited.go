@@ -0,0 +1,426 @@
+package crawler
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"webcrawler-go/internal/dependencies"
+)
+
+// VisitedStore keeps track of the links that the crawler has visited. Implementations must be safe for concurrent use.
+type VisitedStore interface {
+	MarkIfNew(url string) bool        // Marks the link as visited, returning false if it already was.
+	Has(url string) bool              // Reports whether the link has been visited.
+	Len() int                         // Returns the no. of visited links.
+	Iterate(fn func(url string) bool) // Calls fn for every visited link, in no particular order, until fn returns false.
+}
+
+// MemoryReporter is implemented by visited stores that can tell how much memory they're holding on to.
+type MemoryReporter interface {
+	MemoryUsage() uint64 // Returns the (estimated) no. of bytes held.
+}
+
+const (
+	VisitedStoreMemory  = "memory"
+	VisitedStoreSharded = "sharded"
+	VisitedStoreFile    = "file"
+	VisitedStoreBloom   = "bloom"
+)
+
+// NewVisitedStore builds the store set by VISITED_STORE.
+func NewVisitedStore(cfg *dependencies.Config) (VisitedStore, error) {
+	switch cfg.VisitedStore {
+	case VisitedStoreMemory, "":
+		return NewMemoryVisitedStore(), nil
+	case VisitedStoreSharded:
+		return NewShardedVisitedStore(64), nil
+	case VisitedStoreFile:
+		return NewFileVisitedStore(cfg.VisitedStorePath)
+	case VisitedStoreBloom:
+		return NewBloomVisitedStore(cfg.VisitedExpectedUrls, cfg.VisitedFalsePositiveRate), nil
+	default:
+		return nil, fmt.Errorf("unknown visited store %q", cfg.VisitedStore)
+	}
+}
+
+// Go doesn't expose map internals, so this is a rough per-entry cost on top of the URL's bytes: the string header,
+// the value and the map's bucket overhead.
+const mapEntryOverhead = 48
+
+// MemoryVisitedStore is a map guarded by a single mutex.
+type MemoryVisitedStore struct {
+	urls  map[string]bool
+	bytes uint64
+	lock  sync.RWMutex
+}
+
+func NewMemoryVisitedStore() *MemoryVisitedStore {
+	return &MemoryVisitedStore{urls: make(map[string]bool)}
+}
+
+func (s *MemoryVisitedStore) MarkIfNew(url string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.urls[url] {
+		return false
+	}
+	s.urls[url] = true
+	s.bytes += uint64(len(url)) + mapEntryOverhead
+
+	return true
+}
+
+func (s *MemoryVisitedStore) Has(url string) bool {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.urls[url]
+}
+
+func (s *MemoryVisitedStore) Len() int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return len(s.urls)
+}
+
+func (s *MemoryVisitedStore) MemoryUsage() uint64 {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.bytes
+}
+
+func (s *MemoryVisitedStore) Iterate(fn func(url string) bool) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	for u := range s.urls {
+		if !fn(u) {
+			return
+		}
+	}
+}
+
+// ShardedVisitedStore spreads links over several independently locked maps, which cuts down on lock contention when
+// many workers mark links at the same time.
+type ShardedVisitedStore struct {
+	shards []*MemoryVisitedStore
+}
+
+func NewShardedVisitedStore(shards int) *ShardedVisitedStore {
+	if shards < 1 {
+		shards = 1
+	}
+
+	s := &ShardedVisitedStore{shards: make([]*MemoryVisitedStore, shards)}
+	for i := range s.shards {
+		s.shards[i] = NewMemoryVisitedStore()
+	}
+
+	return s
+}
+
+func (s *ShardedVisitedStore) shard(url string) *MemoryVisitedStore {
+	return s.shards[hash(url)%uint32(len(s.shards))]
+}
+
+func (s *ShardedVisitedStore) MarkIfNew(url string) bool {
+	return s.shard(url).MarkIfNew(url)
+}
+
+func (s *ShardedVisitedStore) Has(url string) bool {
+	return s.shard(url).Has(url)
+}
+
+func (s *ShardedVisitedStore) Len() int {
+	n := 0
+	for _, shard := range s.shards {
+		n += shard.Len()
+	}
+	return n
+}
+
+func (s *ShardedVisitedStore) MemoryUsage() uint64 {
+	var n uint64
+	for _, shard := range s.shards {
+		n += shard.MemoryUsage()
+	}
+	return n
+}
+
+func (s *ShardedVisitedStore) Iterate(fn func(url string) bool) {
+	for _, shard := range s.shards {
+		stopped := false
+		shard.Iterate(func(url string) bool {
+			stopped = !fn(url)
+			return !stopped
+		})
+		if stopped {
+			return
+		}
+	}
+}
+
+// The no. of bucket files a FileVisitedStore spreads its links over. It must stay the same for existing stores to be readable.
+const fileVisitedStoreBuckets = 256
+
+// A rough per-link cost of a FileVisitedStore's index: the fingerprint, the offset and the map's bucket overhead.
+const fileIndexEntryOverhead = 40
+
+// FileVisitedStore keeps visited links on disk instead of in memory, for crawls too large to fit in RAM.
+// Links are hashed into bucket files, one link per line. Each bucket keeps an in-memory index from a 64-bit fingerprint of
+// each link to where it starts in the file, so a lookup for a new link never touches the disk, and a lookup for a known one
+// reads that single line back to rule out fingerprint collisions. The index costs a few dozen bytes per link, whatever its length.
+//
+// A store opened on a directory that already holds buckets carries on from them, dropping any line that was cut short by a
+// crash. Call Reset to start afresh, as the CLI does on every run, since the store may also hold links that were still in
+// flight when the crawl stopped.
+type FileVisitedStore struct {
+	buckets []*fileBucket
+	count   atomic.Int64
+}
+
+type fileBucket struct {
+	file       *os.File
+	size       int64
+	index      map[uint64]int64   // The offset of the first link with each fingerprint.
+	collisions map[uint64][]int64 // The offsets of any further links that share a fingerprint, which should be vanishingly rare.
+	lock       sync.Mutex
+}
+
+func NewFileVisitedStore(dir string) (*FileVisitedStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	s := &FileVisitedStore{buckets: make([]*fileBucket, fileVisitedStoreBuckets)}
+	for i := range s.buckets {
+		f, err := os.OpenFile(filepath.Join(dir, fmt.Sprintf("visited-%03d.txt", i)), os.O_RDWR|os.O_CREATE, 0o644)
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		b := &fileBucket{file: f, index: make(map[uint64]int64), collisions: make(map[uint64][]int64)}
+		s.buckets[i] = b
+
+		// Index the links carried over from a previous run.
+		n, err := b.load()
+		if err != nil {
+			s.Close()
+			return nil, err
+		}
+		s.count.Add(int64(n))
+	}
+
+	return s, nil
+}
+
+func (s *FileVisitedStore) bucket(url string) *fileBucket {
+	return s.buckets[hash(url)%fileVisitedStoreBuckets]
+}
+
+// MarkIfNew appends the link to its bucket unless it's already there. Should the disk fail us, the link is treated as new
+// so that the crawl carries on, at the cost of possibly visiting it twice, but it isn't stored or counted.
+func (s *FileVisitedStore) MarkIfNew(url string) bool {
+	b := s.bucket(url)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	fp := fingerprint(url)
+	if b.has(url, fp) {
+		return false
+	}
+
+	line := []byte(url + "\n")
+	if n, err := b.file.WriteAt(line, b.size); err != nil || n < len(line) {
+		log.Printf("unable to store visited link %s - %v\n", url, err)
+		// Drop whatever made it to disk, so that the next link starts on a line of its own.
+		if n > 0 {
+			if err := b.file.Truncate(b.size); err != nil {
+				log.Printf("unable to truncate %s - %v\n", b.file.Name(), err)
+			}
+		}
+		return true
+	}
+
+	b.add(fp, b.size)
+	b.size += int64(len(line))
+	s.count.Add(1)
+
+	return true
+}
+
+func (s *FileVisitedStore) Has(url string) bool {
+	b := s.bucket(url)
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return b.has(url, fingerprint(url))
+}
+
+func (s *FileVisitedStore) Len() int {
+	return int(s.count.Load())
+}
+
+func (s *FileVisitedStore) MemoryUsage() uint64 {
+	return uint64(s.count.Load()) * fileIndexEntryOverhead
+}
+
+func (s *FileVisitedStore) Iterate(fn func(url string) bool) {
+	for _, b := range s.buckets {
+		stopped := false
+		b.lock.Lock()
+		err := b.scan(func(url string, _ int64) bool {
+			stopped = !fn(url)
+			return !stopped
+		})
+		b.lock.Unlock()
+		if err != nil {
+			log.Printf("unable to read visited links from %s - %v\n", b.file.Name(), err)
+		}
+		if stopped {
+			return
+		}
+	}
+}
+
+// Reset forgets every visited link.
+func (s *FileVisitedStore) Reset() error {
+	for _, b := range s.buckets {
+		b.lock.Lock()
+		err := b.file.Truncate(0)
+		if err == nil {
+			b.size = 0
+			b.index = make(map[uint64]int64)
+			b.collisions = make(map[uint64][]int64)
+		}
+		b.lock.Unlock()
+		if err != nil {
+			return err
+		}
+	}
+	s.count.Store(0)
+
+	return nil
+}
+
+func (s *FileVisitedStore) Close() error {
+	var firstErr error
+	for _, b := range s.buckets {
+		if b == nil {
+			continue
+		}
+		if err := b.file.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// load indexes the links already in the bucket file and returns how many there are. A last line without a newline was cut
+// short, so it's truncated away.
+func (b *fileBucket) load() (int, error) {
+	info, err := b.file.Stat()
+	if err != nil {
+		return 0, err
+	}
+	b.size = info.Size()
+
+	n := 0
+	end := int64(0)
+	err = b.scan(func(url string, offset int64) bool {
+		b.add(fingerprint(url), offset)
+		end = offset + int64(len(url)) + 1
+		n++
+		return true
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	if end < b.size {
+		if err := b.file.Truncate(end); err != nil {
+			return 0, err
+		}
+		b.size = end
+	}
+
+	return n, nil
+}
+
+// has reports whether the link is in the bucket. The caller must hold the bucket's lock.
+func (b *fileBucket) has(url string, fp uint64) bool {
+	offset, ok := b.index[fp]
+	if !ok {
+		return false
+	}
+	if b.matches(url, offset) {
+		return true
+	}
+	for _, offset := range b.collisions[fp] {
+		if b.matches(url, offset) {
+			return true
+		}
+	}
+	return false
+}
+
+func (b *fileBucket) add(fp uint64, offset int64) {
+	if _, ok := b.index[fp]; ok {
+		b.collisions[fp] = append(b.collisions[fp], offset)
+		return
+	}
+	b.index[fp] = offset
+}
+
+// matches reports whether the line at offset holds the link. Reading one byte past the link's length takes in the newline,
+// which rules out longer links that merely start the same way.
+func (b *fileBucket) matches(url string, offset int64) bool {
+	line := make([]byte, len(url)+1)
+	if _, err := b.file.ReadAt(line, offset); err != nil && err != io.EOF {
+		log.Printf("unable to read visited links from %s - %v\n", b.file.Name(), err)
+		return false
+	}
+	return string(line[:len(url)]) == url && line[len(url)] == '\n'
+}
+
+// scan reads the bucket line by line from the start, along with the offset each line starts at. A last line without a
+// newline is left out. The caller must hold the bucket's lock.
+func (b *fileBucket) scan(fn func(url string, offset int64) bool) error {
+	r := bufio.NewReader(io.NewSectionReader(b.file, 0, b.size))
+	offset := int64(0)
+	for {
+		line, err := r.ReadString('\n')
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if !fn(line[:len(line)-1], offset) {
+			return nil
+		}
+		offset += int64(len(line))
+	}
+}
+
+func hash(s string) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// fingerprint is a 64-bit hash of the link, independent of the 32-bit one that picks its bucket.
+func fingerprint(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	return h.Sum64()
+}
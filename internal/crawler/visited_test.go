@@ -0,0 +1,194 @@
+package crawler
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"webcrawler-go/internal/dependencies"
+)
+
+func TestVisitedStore(t *testing.T) {
+	stores := map[string]func(t *testing.T) VisitedStore{
+		"memory": func(t *testing.T) VisitedStore {
+			return NewMemoryVisitedStore()
+		},
+		"sharded": func(t *testing.T) VisitedStore {
+			return NewShardedVisitedStore(8)
+		},
+		"file": func(t *testing.T) VisitedStore {
+			s, err := NewFileVisitedStore(t.TempDir())
+			require.NoError(t, err)
+			t.Cleanup(func() { s.Close() })
+			return s
+		},
+	}
+
+	for name, newStore := range stores {
+		t.Run(name, func(t *testing.T) {
+			t.Run("marks links only once", func(t *testing.T) {
+				s := newStore(t)
+
+				assert.False(t, s.Has("https://monzo.com/"))
+				assert.True(t, s.MarkIfNew("https://monzo.com/"))
+				assert.False(t, s.MarkIfNew("https://monzo.com/"))
+				assert.True(t, s.Has("https://monzo.com/"))
+				assert.False(t, s.Has("https://monzo.com/help/"))
+				assert.Equal(t, 1, s.Len())
+			})
+
+			t.Run("iterates over every link", func(t *testing.T) {
+				s := newStore(t)
+
+				expected := make([]string, 100)
+				for i := range expected {
+					expected[i] = fmt.Sprintf("https://monzo.com/%d/", i)
+					s.MarkIfNew(expected[i])
+				}
+
+				var urls []string
+				s.Iterate(func(url string) bool {
+					urls = append(urls, url)
+					return true
+				})
+				assert.ElementsMatch(t, expected, urls)
+				assert.Equal(t, 100, s.Len())
+
+				n := 0
+				s.Iterate(func(url string) bool {
+					n++
+					return n < 10
+				})
+				assert.Equal(t, 10, n, "stops when told to")
+			})
+
+			t.Run("is safe for concurrent use", func(t *testing.T) {
+				s := newStore(t)
+
+				var (
+					wg     sync.WaitGroup
+					marked atomic.Int64
+				)
+				for w := 0; w < 8; w++ {
+					wg.Add(1)
+					go func() {
+						defer wg.Done()
+						for i := 0; i < 200; i++ {
+							if s.MarkIfNew(fmt.Sprintf("https://monzo.com/%d/", i)) {
+								marked.Add(1)
+							}
+						}
+					}()
+				}
+				wg.Wait()
+
+				assert.Equal(t, int64(200), marked.Load())
+				assert.Equal(t, 200, s.Len())
+			})
+		})
+	}
+}
+
+func TestFileVisitedStore(t *testing.T) {
+	t.Run("carries on from an existing directory", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s, err := NewFileVisitedStore(dir)
+		require.NoError(t, err)
+		s.MarkIfNew("https://monzo.com/")
+		s.MarkIfNew("https://monzo.com/help/")
+		require.NoError(t, s.Close())
+
+		s, err = NewFileVisitedStore(dir)
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Equal(t, 2, s.Len())
+		assert.True(t, s.Has("https://monzo.com/help/"))
+		assert.False(t, s.MarkIfNew("https://monzo.com/"))
+		assert.True(t, s.MarkIfNew("https://monzo.com/switch/"))
+	})
+
+	t.Run("drops a line cut short by a crash", func(t *testing.T) {
+		dir := t.TempDir()
+
+		s, err := NewFileVisitedStore(dir)
+		require.NoError(t, err)
+		s.MarkIfNew("https://monzo.com/")
+		f := s.bucket("https://monzo.com/").file
+		_, err = f.WriteAt([]byte("https://monzo.com/hel"), s.bucket("https://monzo.com/").size)
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		s, err = NewFileVisitedStore(dir)
+		require.NoError(t, err)
+		defer s.Close()
+
+		assert.Equal(t, 1, s.Len())
+		assert.True(t, s.MarkIfNew("https://monzo.com/help/"))
+		assert.True(t, s.Has("https://monzo.com/help/"))
+		assert.True(t, s.Has("https://monzo.com/"))
+	})
+
+	t.Run("checks links against the disk when their fingerprints collide", func(t *testing.T) {
+		s, err := NewFileVisitedStore(t.TempDir())
+		require.NoError(t, err)
+		defer s.Close()
+
+		s.MarkIfNew("https://monzo.com/")
+		b := s.bucket("https://monzo.com/")
+		// Pretend that a link that was never visited has the same fingerprint as one that was.
+		fp := fingerprint("https://monzo.com/help/")
+		b.index[fp] = b.index[fingerprint("https://monzo.com/")]
+
+		assert.False(t, b.has("https://monzo.com/help/", fp))
+		assert.False(t, b.has("https://monzo.com", fingerprint("https://monzo.com/")))
+		assert.True(t, b.has("https://monzo.com/", fingerprint("https://monzo.com/")))
+	})
+
+	t.Run("doesn't count links it failed to store", func(t *testing.T) {
+		s, err := NewFileVisitedStore(t.TempDir())
+		require.NoError(t, err)
+		require.NoError(t, s.Close())
+
+		assert.True(t, s.MarkIfNew("https://monzo.com/"))
+		assert.Zero(t, s.Len())
+		assert.False(t, s.Has("https://monzo.com/"))
+	})
+
+	t.Run("forgets every link when reset", func(t *testing.T) {
+		s, err := NewFileVisitedStore(t.TempDir())
+		require.NoError(t, err)
+		defer s.Close()
+
+		s.MarkIfNew("https://monzo.com/")
+		require.NoError(t, s.Reset())
+
+		assert.Zero(t, s.Len())
+		assert.False(t, s.Has("https://monzo.com/"))
+		assert.True(t, s.MarkIfNew("https://monzo.com/"))
+	})
+}
+
+func TestNewVisitedStore(t *testing.T) {
+	cfg := &dependencies.Config{VisitedStore: VisitedStoreSharded}
+	s, err := NewVisitedStore(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &ShardedVisitedStore{}, s)
+
+	cfg = &dependencies.Config{VisitedStore: VisitedStoreFile, VisitedStorePath: t.TempDir()}
+	s, err = NewVisitedStore(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &FileVisitedStore{}, s)
+	s.(*FileVisitedStore).Close()
+
+	cfg = &dependencies.Config{VisitedStore: VisitedStoreBloom, VisitedExpectedUrls: 1000, VisitedFalsePositiveRate: 0.01}
+	s, err = NewVisitedStore(cfg)
+	require.NoError(t, err)
+	assert.IsType(t, &BloomVisitedStore{}, s)
+
+	_, err = NewVisitedStore(&dependencies.Config{VisitedStore: "redis"})
+	assert.ErrorContains(t, err, `unknown visited store "redis"`)
+}
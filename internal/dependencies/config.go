@@ -6,14 +6,68 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	MaxCrawlConcurrencyLevel int `env:"MAX_CRAWL_CONCURRENCY_LEVEL" envDefault:"-1"` // Limit the no. of goroutines that can run at a time.
-	MaxCrawlDepth            int `env:"MAX_CRAWL_DEPTH" envDefault:"-1"`             // Limit the depth of pages/links the crawler should process.
-	MaxLoggedUrls            int `env:"MAX_LOGGED_URLS" envDefault:"20"`             // Limit the amount of pending links printed to the console.
+	MaxCrawlConcurrencyLevel int           `env:"MAX_CRAWL_CONCURRENCY_LEVEL" envDefault:"-1"` // Limit the no. of goroutines that can run at a time.
+	MaxCrawlDepth            int           `env:"MAX_CRAWL_DEPTH" envDefault:"-1"`             // Limit the depth of pages/links the crawler should process.
+	MaxLoggedUrls            int           `env:"MAX_LOGGED_URLS" envDefault:"20"`             // Limit the amount of pending links printed to the console.
+	MaxCrawlDuration         time.Duration `env:"MAX_CRAWL_DURATION" envDefault:"0"`           // Limit how long the crawler runs for before stopping gracefully.
+	RespectRobotsTxt         bool          `env:"RESPECT_ROBOTS_TXT" envDefault:"true"`        // Skip over links that the site's robots.txt disallows.
+	RespectNoFollow          bool          `env:"RESPECT_NOFOLLOW" envDefault:"false"`         // Skip over rel="nofollow" links and the links on pages whose robots directives say nofollow.
+	UserAgent                string        `env:"USER_AGENT" envDefault:"webcrawler-go/1.0"`   // Identify the crawler to sites and their robots.txt.
+
+	CrawlStrategy         string   `env:"CRAWL_STRATEGY" envDefault:"bfs"`          // The order in which bounded crawls visit pending links: bfs, dfs or priority.
+	CrawlPriority         string   `env:"CRAWL_PRIORITY" envDefault:"depth"`        // How the priority strategy scores links: depth, inlinks or pattern.
+	CrawlPriorityPatterns []string `env:"CRAWL_PRIORITY_PATTERNS" envSeparator:" "` // Space-separated regexes for the pattern priority, most important first.
+
+	VisitedStore             string  `env:"VISITED_STORE" envDefault:"memory"`              // Where visited links are kept: memory, sharded, file or bloom.
+	VisitedStorePath         string  `env:"VISITED_STORE_PATH" envDefault:".visited"`       // The directory the file store keeps its buckets in.
+	VisitedExpectedUrls      int     `env:"VISITED_EXPECTED_URLS" envDefault:"1000000"`     // The no. of links the bloom store is initially sized for. It grows past this as needed.
+	VisitedFalsePositiveRate float64 `env:"VISITED_FALSE_POSITIVE_RATE" envDefault:"0.001"` // The rate at which the bloom store wrongly reports unvisited links as visited.
+
+	ScopeHosts        []string `env:"SCOPE_HOSTS" envSeparator:" "`                           // Only follow links to these hosts, e.g. "*.monzo.com", rather than to the same site.
+	ScopePathPrefixes []string `env:"SCOPE_PATH_PREFIXES" envSeparator:" "`                   // Only follow links whose path starts with any of these prefixes.
+	ScopeInclude      []string `env:"SCOPE_INCLUDE" envSeparator:" "`                         // Only follow links that match any of these regexes.
+	ScopeExclude      []string `env:"SCOPE_EXCLUDE" envSeparator:" "`                         // Never follow links that match any of these regexes.
+	ScopeSchemes      []string `env:"SCOPE_SCHEMES" envSeparator:" " envDefault:"http https"` // Only follow links with these schemes.
+
+	LinkKinds       []string `env:"LINK_KINDS" envSeparator:" " envDefault:"navigation"`        // The kinds of links picked up from pages: navigation, stylesheet, script, image, font, media, iframe or form.
+	FollowLinkKinds []string `env:"FOLLOW_LINK_KINDS" envSeparator:" " envDefault:"navigation"` // The kinds of links that are crawled. Links of other kinds are only checked to work.
+
+	UrlStripParams   []string `env:"URL_STRIP_PARAMS" envSeparator:" " envDefault:"utm_* gclid fbclid msclkid jsessionid phpsessid sid"` // Tracking and session parameters removed from links. "utm_*" matches any parameter starting with "utm_".
+	UrlTrailingSlash string   `env:"URL_TRAILING_SLASH" envDefault:"keep"`                                                               // What's done with trailing slashes in links: keep, add or strip.
+	UrlSortQuery     bool     `env:"URL_SORT_QUERY" envDefault:"true"`                                                                   // Sort the query parameters of links by name.
+
+	TrapMaxPathSegments     int `env:"TRAP_MAX_PATH_SEGMENTS" envDefault:"0"`     // Quarantine links whose path has more segments than this. 0 disables it.
+	TrapMaxRepeatedSegments int `env:"TRAP_MAX_REPEATED_SEGMENTS" envDefault:"0"` // Quarantine links whose path has the same segment more times than this. 0 disables it.
+	TrapMaxQueryVariants    int `env:"TRAP_MAX_QUERY_VARIANTS" envDefault:"0"`    // Quarantine links to a path once this many of its query strings were crawled. 0 disables it.
+	TrapMaxUrlsPerPattern   int `env:"TRAP_MAX_URLS_PER_PATTERN" envDefault:"0"`  // Quarantine links once this many links with the same path, ignoring numbers, were crawled. 0 disables it.
+
+	CheckpointPath     string        `env:"CHECKPOINT_PATH"`                      // Save the progress of bounded crawls to this file, so that they can be resumed with -resume.
+	CheckpointInterval time.Duration `env:"CHECKPOINT_INTERVAL" envDefault:"30s"` // How often the checkpoint is saved, on top of when the crawl stops.
+
+	HttpTimeout               time.Duration     `env:"HTTP_TIMEOUT" envDefault:"30s"`                 // Limit the total time spent on a single HTTP request.
+	HttpResponseHeaderTimeout time.Duration     `env:"HTTP_RESPONSE_HEADER_TIMEOUT" envDefault:"10s"` // Limit the time spent waiting for a response's headers.
+	HttpTlsHandshakeTimeout   time.Duration     `env:"HTTP_TLS_HANDSHAKE_TIMEOUT" envDefault:"10s"`   // Limit the time spent on TLS handshakes.
+	HttpHeaders               map[string]string `env:"HTTP_HEADERS"`                                  // Extra headers sent with every request, e.g. "Accept-Language:en-GB,From:me@example.com".
+	HttpProxyUrl              string            `env:"HTTP_PROXY_URL"`                                // Route requests through this proxy instead of the one set by HTTP_PROXY/HTTPS_PROXY.
+	HttpMaxIdleConnsPerHost   int               `env:"HTTP_MAX_IDLE_CONNS_PER_HOST" envDefault:"10"`  // Limit the no. of keep-alive connections kept open per host.
+	HttpMaxRedirects          int               `env:"HTTP_MAX_REDIRECTS" envDefault:"10"`            // Limit the no. of redirects followed per link before it's reported as failed.
+	HttpLongRedirectChain     int               `env:"HTTP_LONG_REDIRECT_CHAIN" envDefault:"2"`       // Report links that take at least this many redirects to resolve.
+	HttpMaxBodySize           int64             `env:"HTTP_MAX_BODY_SIZE" envDefault:"10485760"`      // Limit the no. of bytes read from a response body. 0 disables it.
+
+	RetryMaxAttempts int           `env:"RETRY_MAX_ATTEMPTS" envDefault:"3"`   // Limit the no. of attempts per link when fetches fail intermittently. 1 disables retries.
+	RetryBaseDelay   time.Duration `env:"RETRY_BASE_DELAY" envDefault:"500ms"` // Wait this long before the first retry, doubling for every retry after that.
+	RetryMaxDelay    time.Duration `env:"RETRY_MAX_DELAY" envDefault:"30s"`    // Cap the wait between two attempts. Links whose Retry-After asks for longer aren't retried.
+	RetryJitter      float64       `env:"RETRY_JITTER" envDefault:"0.2"`       // Randomise each wait by up to this fraction (0-1).
+
+	RateLimitRps      float64       `env:"RATE_LIMIT_RPS" envDefault:"0"`       // Limit the no. of requests per second sent to each host. 0 leaves it unbounded.
+	RateLimitBurst    int           `env:"RATE_LIMIT_BURST" envDefault:"1"`     // Let each host take this many requests back-to-back before the rate limit kicks in.
+	RateLimitMinDelay time.Duration `env:"RATE_LIMIT_MIN_DELAY" envDefault:"0"` // Space out consecutive requests to the same host by at least this long.
 }
 
 func LoadEnv() *Config {
@@ -0,0 +1,74 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Checker verifies that links work without following them. It sends a HEAD request first, and falls back to a GET when
+// the server says it doesn't support HEAD (405 or 501) or the request fails without a response, as some servers drop HEAD
+// requests altogether. Any other status is taken as the link's. The body is never read, so Page.Links is always
+// empty. It shares its fetcher's client, headers and user-agent.
+type Checker struct {
+	fetcher *Fetcher
+}
+
+func NewChecker(fetcher *Fetcher) *Checker {
+	return &Checker{
+		fetcher: fetcher,
+	}
+}
+
+// Fetch checks the link at targetUrl. Any response other than a 2xx, after following redirects, is returned as an
+// HTTPStatusError.
+func (c *Checker) Fetch(ctx context.Context, targetUrl string) (*Page, error) {
+	page, err := c.check(ctx, http.MethodHead, targetUrl)
+	if err == nil || ctx.Err() != nil || !headUnsupported(err) {
+		return page, err
+	}
+
+	return c.check(ctx, http.MethodGet, targetUrl)
+}
+
+// headUnsupported reports whether a failed HEAD request is worth trying again as a GET.
+func headUnsupported(err error) bool {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) {
+		return true
+	}
+
+	return statusErr.StatusCode == http.StatusMethodNotAllowed || statusErr.StatusCode == http.StatusNotImplemented
+}
+
+func (c *Checker) check(ctx context.Context, method, targetUrl string) (*Page, error) {
+	resp, err := c.fetcher.do(ctx, method, targetUrl)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			URL:        resp.Request.URL.String(),
+			Header:     resp.Header,
+		}
+	}
+
+	page := &Page{
+		URL:         resp.Request.URL.String(),
+		Redirects:   redirects(resp.Request),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Links:       []Link{},
+	}
+	if resp.ContentLength > 0 {
+		page.Size = resp.ContentLength
+	}
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		page.LastModified = lastModified
+	}
+
+	return page, nil
+}
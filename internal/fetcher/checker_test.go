@@ -0,0 +1,111 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+// methodServer responds to each method with the given status, and keeps track of the methods it was sent.
+func methodServer(statuses map[string]int) (*httptest.Server, func() []string) {
+	var (
+		methods []string
+		lock    sync.Mutex
+	)
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		methods = append(methods, r.Method)
+		lock.Unlock()
+
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(statuses[r.Method])
+		fmt.Fprint(w, `<a href="/about/">About</a>`)
+	}))
+	return testServer, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return methods
+	}
+}
+
+func TestChecker_Fetch(t *testing.T) {
+	t.Run("checks links with a HEAD request", func(t *testing.T) {
+		testServer, methods := methodServer(map[string]int{http.MethodHead: http.StatusOK, http.MethodGet: http.StatusOK})
+		defer testServer.Close()
+
+		page, err := NewChecker(NewFetcher()).Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+		assert.Equal(t, "text/html", page.ContentType)
+		assert.Empty(t, page.Links, "links should never be followed")
+		assert.Equal(t, []string{http.MethodHead}, methods())
+	})
+
+	t.Run("falls back to a GET request when HEAD isn't supported", func(t *testing.T) {
+		for _, status := range []int{http.StatusMethodNotAllowed, http.StatusNotImplemented} {
+			testServer, methods := methodServer(map[string]int{http.MethodHead: status, http.MethodGet: http.StatusOK})
+
+			page, err := NewChecker(NewFetcher()).Fetch(context.Background(), testServer.URL)
+			require.NoError(t, err)
+
+			assert.Equal(t, http.StatusOK, page.StatusCode)
+			assert.Empty(t, page.Links)
+			assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods())
+			testServer.Close()
+		}
+	})
+
+	t.Run("falls back to a GET request when HEAD gets no response", func(t *testing.T) {
+		var methods []string
+		var lock sync.Mutex
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			lock.Lock()
+			methods = append(methods, r.Method)
+			lock.Unlock()
+
+			if r.Method == http.MethodHead {
+				conn, _, _ := w.(http.Hijacker).Hijack()
+				conn.Close()
+				return
+			}
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer testServer.Close()
+
+		page, err := NewChecker(NewFetcher()).Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+		lock.Lock()
+		defer lock.Unlock()
+		assert.Equal(t, []string{http.MethodHead, http.MethodGet}, methods)
+	})
+
+	t.Run("returns the status of broken links", func(t *testing.T) {
+		testServer, methods := methodServer(map[string]int{http.MethodHead: http.StatusNotFound, http.MethodGet: http.StatusNotFound})
+		defer testServer.Close()
+
+		_, err := NewChecker(NewFetcher()).Fetch(context.Background(), testServer.URL)
+
+		var statusErr *HTTPStatusError
+		require.True(t, errors.As(err, &statusErr))
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+		assert.Equal(t, []string{http.MethodHead}, methods(), "a 404 to HEAD shouldn't be checked again with GET")
+	})
+
+	t.Run("takes any 2xx as working", func(t *testing.T) {
+		testServer, _ := methodServer(map[string]int{http.MethodHead: http.StatusNoContent})
+		defer testServer.Close()
+
+		page, err := NewChecker(NewFetcher()).Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+		assert.Equal(t, http.StatusNoContent, page.StatusCode)
+	})
+}
@@ -0,0 +1,226 @@
+package fetcher
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// cssLinks returns the raw URLs in a stylesheet or a style attribute, in the order they appear: @import targets as
+// stylesheets, url()s in @font-face rules as fonts and any other url() as an image. url()s that point to an element of
+// the same document, e.g. url(#gradient), are left out. Comments are ignored, and so are strings outside of @import.
+func cssLinks(css string) []Link {
+	var (
+		links       = make([]Link, 0)
+		depth       int  // How many blocks deep the scanner is.
+		fontFace    bool // Whether the next block is an @font-face rule.
+		fontFaceEnd = -1 // The depth that ends the @font-face rule the scanner is in, if any.
+	)
+
+	add := func(u string, kind LinkKind) {
+		if u != "" && !strings.HasPrefix(u, "#") {
+			links = append(links, Link{URL: u, Kind: kind})
+		}
+	}
+
+	for i := 0; i < len(css); {
+		switch c := css[i]; {
+		case strings.HasPrefix(css[i:], "/*"):
+			end := strings.Index(css[i+2:], "*/")
+			if end < 0 {
+				return links
+			}
+			i += end + 4
+		case c == '"' || c == '\'':
+			_, i = cssString(css, i)
+		case c == '\\':
+			// An escaped character is never the start of anything.
+			_, i = cssEscape(css, i)
+		case c == '{':
+			depth++
+			if fontFace {
+				fontFace, fontFaceEnd = false, depth-1
+			}
+			i++
+		case c == '}':
+			if depth--; depth == fontFaceEnd {
+				fontFaceEnd = -1
+			}
+			i++
+		case c == ';':
+			// A statement at-rule such as @charset ends before any block.
+			fontFace = false
+			i++
+		case c == '@':
+			name := cssIdent(css, i+1)
+			i += 1 + len(name)
+			switch strings.ToLower(name) {
+			case "import":
+				u, end, ok := cssImport(css, i)
+				if ok {
+					add(u, KindStylesheet)
+				}
+				i = end
+			case "font-face":
+				fontFace = true
+			}
+		case isCssIdent(c) || c >= utf8.RuneSelf:
+			name := cssIdent(css, i)
+			i += len(name)
+			if !strings.EqualFold(name, "url") || i >= len(css) || css[i] != '(' {
+				continue
+			}
+
+			u, end := cssUrl(css, i+1)
+			if fontFaceEnd >= 0 {
+				add(u, KindFont)
+			} else {
+				add(u, KindImage)
+			}
+			i = end
+		default:
+			i++
+		}
+	}
+
+	return links
+}
+
+// cssImport reads the target of an @import rule that starts at i, right after "@import". It returns where the scanner
+// should carry on from, and whether there was a target.
+func cssImport(css string, i int) (string, int, bool) {
+	i = skipCssSpace(css, i)
+	if i >= len(css) {
+		return "", i, false
+	}
+
+	if css[i] == '"' || css[i] == '\'' {
+		u, end := cssString(css, i)
+		return u, end, true
+	}
+
+	name := cssIdent(css, i)
+	if strings.EqualFold(name, "url") && i+len(name) < len(css) && css[i+len(name)] == '(' {
+		u, end := cssUrl(css, i+len(name)+1)
+		return u, end, true
+	}
+
+	return "", i, false
+}
+
+// cssUrl reads the contents of a url() whose opening parenthesis ends right before i, quoted or not. It returns the URL and
+// where the url() ends.
+func cssUrl(css string, i int) (string, int) {
+	i = skipCssSpace(css, i)
+
+	var u string
+	if i < len(css) && (css[i] == '"' || css[i] == '\'') {
+		u, i = cssString(css, i)
+	} else {
+		var b strings.Builder
+		for i < len(css) && css[i] != ')' {
+			if css[i] == '\\' {
+				r, end := cssEscape(css, i)
+				b.WriteString(r)
+				i = end
+				continue
+			}
+			b.WriteByte(css[i])
+			i++
+		}
+		u = strings.TrimSpace(b.String())
+	}
+
+	// Anything between a quoted URL and the closing parenthesis, e.g. whitespace, isn't part of it.
+	if end := strings.IndexByte(css[i:], ')'); end >= 0 {
+		i += end + 1
+	} else {
+		i = len(css)
+	}
+
+	return strings.TrimSpace(u), i
+}
+
+// cssString reads the quoted string that starts at i, unescaping it. It returns the string and where it ends. Strings
+// that aren't closed end at the end of the line.
+func cssString(css string, i int) (string, int) {
+	quote := css[i]
+	i++
+
+	var b strings.Builder
+	for i < len(css) {
+		switch css[i] {
+		case quote:
+			return b.String(), i + 1
+		case '\n':
+			return b.String(), i
+		case '\\':
+			r, end := cssEscape(css, i)
+			b.WriteString(r)
+			i = end
+		default:
+			b.WriteByte(css[i])
+			i++
+		}
+	}
+	return b.String(), i
+}
+
+// cssEscape reads the escape sequence that starts with the backslash at i, e.g. "\)" or "\29 ". It returns the character
+// it stands for and where it ends. An escaped newline stands for nothing.
+func cssEscape(css string, i int) (string, int) {
+	i++
+	if i >= len(css) {
+		return "", i
+	}
+
+	hex := i
+	for hex < len(css) && hex-i < 6 && isHex(css[hex]) {
+		hex++
+	}
+	if hex > i {
+		code, _ := strconv.ParseUint(css[i:hex], 16, 32)
+		// A single whitespace character after a hex escape only ends it.
+		if hex < len(css) && isCssSpace(css[hex]) {
+			hex++
+		}
+		if code == 0 || code > utf8.MaxRune {
+			return string(utf8.RuneError), hex
+		}
+		return string(rune(code)), hex
+	}
+
+	if css[i] == '\n' {
+		return "", i + 1
+	}
+	_, size := utf8.DecodeRuneInString(css[i:])
+	return css[i : i+size], i + size
+}
+
+// cssIdent returns the identifier that starts at i, if any, e.g. "url" or "font-face".
+func cssIdent(css string, i int) string {
+	end := i
+	for end < len(css) && (isCssIdent(css[end]) || css[end] >= utf8.RuneSelf) {
+		end++
+	}
+	return css[i:end]
+}
+
+func skipCssSpace(css string, i int) int {
+	for i < len(css) && isCssSpace(css[i]) {
+		i++
+	}
+	return i
+}
+
+func isCssIdent(c byte) bool {
+	return c == '-' || c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+func isCssSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isHex(c byte) bool {
+	return ('0' <= c && c <= '9') || ('a' <= c && c <= 'f') || ('A' <= c && c <= 'F')
+}
@@ -0,0 +1,123 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_cssLinks(t *testing.T) {
+	fixtures := []struct {
+		file     string
+		expected []Link
+	}{
+		{
+			file: "quoting.css",
+			expected: []Link{
+				{URL: "/img/unquoted.png", Kind: KindImage},
+				{URL: "/img/double.png", Kind: KindImage},
+				{URL: "/img/single.png", Kind: KindImage},
+				{URL: "/img/padded.png", Kind: KindImage},
+				{URL: "/img/padded quoted.png", Kind: KindImage},
+				{URL: "/img/uppercase.png", Kind: KindImage},
+				{URL: "/img/escaped(1).png", Kind: KindImage},
+				{URL: "/img/hex-escaped.png", Kind: KindImage},
+				{URL: "/img/it's.png", Kind: KindImage},
+				{URL: "/img/first.png", Kind: KindImage},
+				{URL: "/img/second.png", Kind: KindImage},
+				{URL: "../cursors/hand.cur", Kind: KindImage},
+				{URL: "data:image/png;base64,iVBORw0KGgo=", Kind: KindImage},
+				{URL: "/img/bullet.svg", Kind: KindImage},
+			},
+		},
+		{
+			file: "imports.css",
+			expected: []Link{
+				{URL: "/css/double.css", Kind: KindStylesheet},
+				{URL: "/css/single.css", Kind: KindStylesheet},
+				{URL: "/css/unquoted.css", Kind: KindStylesheet},
+				{URL: "/css/quoted-url.css", Kind: KindStylesheet},
+				{URL: "/css/uppercase.css", Kind: KindStylesheet},
+				{URL: "relative.css", Kind: KindStylesheet},
+				{URL: "/img/body.png", Kind: KindImage},
+			},
+		},
+		{
+			file: "fonts.css",
+			expected: []Link{
+				{URL: "/fonts/monzo-sans.woff2", Kind: KindFont},
+				{URL: "/fonts/monzo-sans.woff", Kind: KindFont},
+				{URL: "/fonts/icons.ttf", Kind: KindFont},
+				{URL: "/img/hero.jpg", Kind: KindImage},
+				{URL: "/img/after.png", Kind: KindImage},
+			},
+		},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.file, func(t *testing.T) {
+			css, err := os.ReadFile(filepath.Join("testdata", fixture.file))
+			require.NoError(t, err)
+
+			assert.Equal(t, fixture.expected, cssLinks(string(css)))
+		})
+	}
+
+	t.Run("unterminated markup", func(t *testing.T) {
+		assert.Equal(t, []Link{{URL: "/a.png", Kind: KindImage}, {URL: "/b.png", Kind: KindImage}}, cssLinks(`a { background: url(/a.png); b { background: url("/b.png`))
+		assert.Equal(t, []Link{}, cssLinks(`/* url(/a.png)`))
+	})
+}
+
+func TestFetcher_parse_css(t *testing.T) {
+	targetUrl, err := url.Parse("https://monzo.com/blog/")
+	require.NoError(t, err)
+
+	f := NewFetcher(WithLinkKinds(KindNavigation, KindStylesheet, KindImage))
+	page := &Page{}
+	f.parse(page, `<style>
+	@import "/css/print.css" print;
+	.hero { background: url(hero.jpg); }
+	@font-face { src: url(/fonts/monzo.woff2); }
+</style>
+<a href="/about/" style="background-image: url('/img/about.png')">About</a>
+<template><div style="background: url(/hidden.png)"></div></template>`, targetUrl)
+
+	assert.Equal(t, []Link{
+		{URL: "https://monzo.com/css/print.css", Kind: KindStylesheet, Position: 1},
+		{URL: "https://monzo.com/blog/hero.jpg", Kind: KindImage, Position: 2},
+		{URL: "https://monzo.com/about/", Text: "About", Kind: KindNavigation, Position: 3},
+		{URL: "https://monzo.com/img/about.png", Kind: KindImage, Position: 4},
+	}, page.Links)
+}
+
+func TestFetcher_Fetch_stylesheet(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		css, err := os.ReadFile(filepath.Join("testdata", "imports.css"))
+		require.NoError(t, err)
+
+		w.Header().Set("Content-Type", "text/css; charset=utf-8")
+		w.Write(css)
+	}))
+	defer testServer.Close()
+
+	f := NewFetcher(WithLinkKinds(KindStylesheet))
+	page, err := f.Fetch(context.Background(), testServer.URL+"/css/main.css")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		fmt.Sprintf("%s/css/double.css", testServer.URL),
+		fmt.Sprintf("%s/css/single.css", testServer.URL),
+		fmt.Sprintf("%s/css/unquoted.css", testServer.URL),
+		fmt.Sprintf("%s/css/quoted-url.css", testServer.URL),
+		fmt.Sprintf("%s/css/uppercase.css", testServer.URL),
+		fmt.Sprintf("%s/css/relative.css", testServer.URL),
+	}, linkUrls(page.Links), "urls should be relative to the stylesheet and only of the enabled kinds")
+}
@@ -0,0 +1,72 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+)
+
+// HTTPStatusError is returned when a page responds with anything other than 200 OK.
+type HTTPStatusError struct {
+	StatusCode int
+	URL        string // The final URL of the page, after any redirects.
+	Header     http.Header
+}
+
+func (e *HTTPStatusError) Error() string {
+	return fmt.Sprintf("unexpected status %d %s from %s", e.StatusCode, http.StatusText(e.StatusCode), e.URL)
+}
+
+type ErrorClass string
+
+const (
+	ErrorRetryable ErrorClass = "retryable" // The fetch may succeed if it's tried again later, e.g. 5xx, 429 and timeouts.
+	ErrorPermanent ErrorClass = "permanent" // The fetch will keep failing, e.g. 404, 410 and unknown hosts.
+)
+
+// Classify tells transient fetch errors apart from permanent ones. Errors that aren't recognised are treated as permanent.
+func Classify(err error) ErrorClass {
+	var statusErr *HTTPStatusError
+	if errors.As(err, &statusErr) {
+		switch {
+		case statusErr.StatusCode == http.StatusTooManyRequests,
+			statusErr.StatusCode == http.StatusRequestTimeout,
+			statusErr.StatusCode >= 500 && statusErr.StatusCode != http.StatusNotImplemented:
+			return ErrorRetryable
+		default:
+			return ErrorPermanent
+		}
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		if dnsErr.IsNotFound {
+			return ErrorPermanent
+		}
+		return ErrorRetryable
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return ErrorRetryable
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) {
+		return ErrorRetryable
+	}
+
+	return ErrorPermanent
+}
+
+// IsRetryable reports whether the fetch that returned err is worth trying again.
+func IsRetryable(err error) bool {
+	return err != nil && Classify(err) == ErrorRetryable
+}
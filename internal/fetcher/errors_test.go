@@ -0,0 +1,55 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	statusErr := func(code int) error {
+		return &HTTPStatusError{StatusCode: code, URL: "https://monzo.com/"}
+	}
+
+	cases := []struct {
+		name     string
+		err      error
+		expected ErrorClass
+	}{
+		{"500 Internal Server Error", statusErr(http.StatusInternalServerError), ErrorRetryable},
+		{"502 Bad Gateway", statusErr(http.StatusBadGateway), ErrorRetryable},
+		{"503 Service Unavailable", statusErr(http.StatusServiceUnavailable), ErrorRetryable},
+		{"504 Gateway Timeout", statusErr(http.StatusGatewayTimeout), ErrorRetryable},
+		{"429 Too Many Requests", statusErr(http.StatusTooManyRequests), ErrorRetryable},
+		{"408 Request Timeout", statusErr(http.StatusRequestTimeout), ErrorRetryable},
+		{"501 Not Implemented", statusErr(http.StatusNotImplemented), ErrorPermanent},
+		{"404 Not Found", statusErr(http.StatusNotFound), ErrorPermanent},
+		{"410 Gone", statusErr(http.StatusGone), ErrorPermanent},
+		{"403 Forbidden", statusErr(http.StatusForbidden), ErrorPermanent},
+		{"wrapped status error", fmt.Errorf("crawl failed: %w", statusErr(http.StatusBadGateway)), ErrorRetryable},
+		{"unknown host", &url.Error{Op: "Get", URL: "https://localhost.org/", Err: &net.DNSError{Err: "no such host", IsNotFound: true}}, ErrorPermanent},
+		{"DNS server timeout", &url.Error{Op: "Get", URL: "https://monzo.com/", Err: &net.DNSError{Err: "i/o timeout", IsTimeout: true}}, ErrorRetryable},
+		{"context deadline", fmt.Errorf("fetching: %w", context.DeadlineExceeded), ErrorRetryable},
+		{"connection reset", &url.Error{Op: "Get", URL: "https://monzo.com/", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, ErrorRetryable},
+		{"connection refused", &url.Error{Op: "Get", URL: "https://monzo.com/", Err: &net.OpError{Op: "dial", Err: syscall.ECONNREFUSED}}, ErrorRetryable},
+		{"malformed URL", &url.Error{Op: "Get", URL: "MALFORMED", Err: errors.New("unsupported protocol scheme")}, ErrorPermanent},
+		{"unknown error", errors.New("something went wrong"), ErrorPermanent},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.Equal(t, c.expected, Classify(c.err))
+			assert.Equal(t, c.expected == ErrorRetryable, IsRetryable(c.err))
+		})
+	}
+
+	t.Run("nil error", func(t *testing.T) {
+		assert.False(t, IsRetryable(nil))
+	})
+}
@@ -1,92 +1,623 @@
 package fetcher
 
 import (
-	"html"
+	"context"
+	"errors"
 	"io"
 	"log"
+	"mime"
 	"net/http"
 	"net/url"
-	"regexp"
 	"strings"
+	"time"
+
+	"golang.org/x/net/html"
 )
 
 type IFetcher interface {
-	Fetch(targetUrl string) ([]string, error)
+	Fetch(ctx context.Context, targetUrl string) (*Page, error)
+}
+
+// Page is what was found at a fetched URL.
+type Page struct {
+	URL         string     // The URL the page was fetched from, after following any redirects.
+	Redirects   []Redirect // The redirects that were followed to get to URL, if any.
+	StatusCode  int
+	ContentType string
+	Size        int64  // The size of the response body in bytes.
+	Links       []Link // Every http(s) link of an enabled kind on the page, regardless of where it points to.
+
+	LastModified time.Time // When the page was last modified, according to its Last-Modified header. Zero if unknown.
+	Canonical    string    // The page's canonical URL from <link rel="canonical">, if any.
+	NoIndex      bool      // Whether the page asked to be kept out of search indexes, through meta robots or X-Robots-Tag.
+	NoFollow     bool      // Whether the page asked for its links not to be followed, through meta robots or X-Robots-Tag.
+
+	Anchors []string // The ids and <a name>s on the page that a link's #fragment can point to, in the order they appear.
 }
 
-type Fetcher struct{}
+// Link is a link found on a page.
+type Link struct {
+	URL      string
+	Text     string   // The link's anchor text, or an image's alt text, with whitespace collapsed.
+	Kind     LinkKind // What the link is used for, e.g. navigation or an image.
+	Position int      // Where the link appears on the page, counting from 1 for the first link.
+	NoFollow bool     // Whether the link asked not to be followed with rel="nofollow".
+}
 
-func NewFetcher() *Fetcher {
-	return &Fetcher{}
+type Fetcher struct {
+	client      *http.Client
+	userAgent   string
+	headers     map[string]string
+	kinds       map[LinkKind]bool
+	maxBodySize int64
 }
 
-func (f *Fetcher) Fetch(rawTargetUrl string) ([]string, error) {
-	targetUrl, err := url.Parse(rawTargetUrl)
+// defaultMaxBodySize is the no. of bytes read from a response body by default.
+const defaultMaxBodySize = 10 << 20
+
+type options struct {
+	timeout               time.Duration
+	responseHeaderTimeout time.Duration
+	tlsHandshakeTimeout   time.Duration
+	userAgent             string
+	headers               map[string]string
+	proxyUrl              *url.URL
+	maxIdleConnsPerHost   int
+	maxRedirects          int
+	linkKinds             []LinkKind
+	maxBodySize           int64
+}
+
+type Option func(o *options)
+
+// WithTimeout limits the total time of a request, including reading the response body.
+func WithTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.timeout = d
+	}
+}
+
+// WithResponseHeaderTimeout limits the time spent waiting for the response headers once the request is sent.
+func WithResponseHeaderTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.responseHeaderTimeout = d
+	}
+}
+
+// WithTLSHandshakeTimeout limits the time spent on the TLS handshake.
+func WithTLSHandshakeTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.tlsHandshakeTimeout = d
+	}
+}
+
+// WithUserAgent sets the User-Agent header sent with every request.
+func WithUserAgent(userAgent string) Option {
+	return func(o *options) {
+		o.userAgent = userAgent
+	}
+}
+
+// WithHeaders sets extra headers sent with every request.
+func WithHeaders(headers map[string]string) Option {
+	return func(o *options) {
+		o.headers = headers
+	}
+}
+
+// WithProxy routes every request through the given proxy instead of the one set in the environment (HTTP_PROXY etc.).
+func WithProxy(proxyUrl *url.URL) Option {
+	return func(o *options) {
+		o.proxyUrl = proxyUrl
+	}
+}
+
+// WithMaxIdleConnsPerHost sets the no. of keep-alive connections kept open per host.
+func WithMaxIdleConnsPerHost(n int) Option {
+	return func(o *options) {
+		o.maxIdleConnsPerHost = n
+	}
+}
+
+// WithMaxRedirects limits the no. of redirects followed per request. By default, it's 10.
+func WithMaxRedirects(n int) Option {
+	return func(o *options) {
+		o.maxRedirects = n
+	}
+}
+
+// WithLinkKinds sets which kinds of links are picked up from pages. By default, only navigation links are.
+func WithLinkKinds(kinds ...LinkKind) Option {
+	return func(o *options) {
+		o.linkKinds = kinds
+	}
+}
+
+// WithMaxBodySize limits the no. of bytes read from a response body, so that a huge page or file can't exhaust memory.
+// Only the links in the first n bytes of a page are picked up. By default, it's 10MiB, and 0 lifts the limit.
+func WithMaxBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// NewFetcher builds a fetcher with its own HTTP client. Any option left unset falls back to the http package's defaults.
+func NewFetcher(opts ...Option) *Fetcher {
+	o := &options{
+		maxRedirects: defaultMaxRedirects,
+		linkKinds:    []LinkKind{KindNavigation},
+		maxBodySize:  defaultMaxBodySize,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if o.responseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = o.responseHeaderTimeout
+	}
+	if o.tlsHandshakeTimeout > 0 {
+		transport.TLSHandshakeTimeout = o.tlsHandshakeTimeout
+	}
+	if o.proxyUrl != nil {
+		transport.Proxy = http.ProxyURL(o.proxyUrl)
+	}
+	if o.maxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = o.maxIdleConnsPerHost
+	}
+
+	kinds := make(map[LinkKind]bool, len(o.linkKinds))
+	for _, k := range o.linkKinds {
+		kinds[k] = true
+	}
+
+	return &Fetcher{
+		client: &http.Client{
+			Transport:     transport,
+			Timeout:       o.timeout,
+			CheckRedirect: checkRedirect(o.maxRedirects),
+		},
+		userAgent:   o.userAgent,
+		headers:     o.headers,
+		kinds:       kinds,
+		maxBodySize: o.maxBodySize,
+	}
+}
+
+// Client returns the underlying HTTP client so that other components (e.g. robots.txt) can share its settings and connection pool.
+func (f *Fetcher) Client() *http.Client {
+	return f.client
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawTargetUrl string) (*Page, error) {
+	if _, err := url.Parse(rawTargetUrl); err != nil {
+		return nil, err
+	}
+
+	page, content, err := f.getHtmlContent(ctx, rawTargetUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	// Relative links are relative to where the page ended up, rather than where it was first requested from.
+	finalUrl, err := url.Parse(page.URL)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case isStylesheet(page.ContentType):
+		f.parseStylesheet(page, content, finalUrl)
+	case IsHtml(page.ContentType), page.ContentType == "" && IsHtml(http.DetectContentType([]byte(content))):
+		f.parse(page, content, finalUrl)
+	default:
+		// Images, PDFs and the like have no links that we pick up.
+		page.Links = []Link{}
+	}
+
+	return page, nil
+}
+
+// do sends a request with the fetcher's headers and user-agent, following any redirects.
+// Redirects that loop or go on for too long are returned as a RedirectError.
+func (f *Fetcher) do(ctx context.Context, method, u string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, u, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	content, err := f.getHtmlContent(rawTargetUrl)
+	for k, v := range f.headers {
+		req.Header.Set(k, v)
+	}
+	if f.userAgent != "" {
+		req.Header.Set("User-Agent", f.userAgent)
+	}
+
+	resp, err := f.client.Do(req)
 	if err != nil {
+		// The client wraps the error returned by checkRedirect in a *url.Error, which only adds the URL that's already in it.
+		var redirectErr *RedirectError
+		if errors.As(err, &redirectErr) {
+			return nil, redirectErr
+		}
 		return nil, err
 	}
 
-	return f.parseAllUrls(content, targetUrl), nil
+	return resp, nil
 }
 
-func (f *Fetcher) getHtmlContent(u string) (string, error) {
-	resp, err := http.Get(u)
+func (f *Fetcher) getHtmlContent(ctx context.Context, u string) (*Page, string, error) {
+	resp, err := f.do(ctx, http.MethodGet, u)
 	if err != nil {
-		return "", err
+		return nil, "", err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != 200 {
-		return "", err
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", &HTTPStatusError{
+			StatusCode: resp.StatusCode,
+			URL:        resp.Request.URL.String(),
+			Header:     resp.Header,
+		}
+	}
+
+	content, size, err := f.readBody(resp)
+	if err != nil {
+		return nil, "", err
+	}
+
+	page := &Page{
+		URL:         resp.Request.URL.String(),
+		Redirects:   redirects(resp.Request),
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Size:        size,
+	}
+	page.NoIndex, page.NoFollow = robotsDirectives(resp.Header.Values("X-Robots-Tag"))
+	if lastModified, err := http.ParseTime(resp.Header.Get("Last-Modified")); err == nil {
+		page.LastModified = lastModified
+	}
+
+	return page, string(content), err
+}
+
+// readBody reads up to maxBodySize bytes of the response body, and returns them along with how many there were. Bodies
+// that can't have links in them, going by their Content-Type, are only counted. Bodies without a Content-Type are read, so
+// that they can be sniffed.
+func (f *Fetcher) readBody(resp *http.Response) ([]byte, int64, error) {
+	body := io.Reader(resp.Body)
+	if f.maxBodySize > 0 {
+		// The extra byte tells a body that's exactly maxBodySize long apart from one that's cut short.
+		body = io.LimitReader(resp.Body, f.maxBodySize+1)
+	}
+
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" && !IsHtml(contentType) && !isStylesheet(contentType) {
+		size, err := io.Copy(io.Discard, body)
+		if f.maxBodySize > 0 && size > f.maxBodySize {
+			size = f.maxBodySize
+		}
+		return nil, size, err
 	}
 
-	content, err := io.ReadAll(resp.Body)
+	content, err := io.ReadAll(body)
 	if err != nil {
-		return "", err
+		return nil, 0, err
+	}
+	if f.maxBodySize > 0 && int64(len(content)) > f.maxBodySize {
+		log.Printf("only reading the first %d bytes of %s\n", f.maxBodySize, resp.Request.URL)
+		content = content[:f.maxBodySize]
 	}
 
-	return html.UnescapeString(string(content)), err
+	return content, int64(len(content)), nil
 }
 
-func (f *Fetcher) parseAllUrls(htmlContent string, targetUrl *url.URL) []string {
-	targetHostname := strings.TrimPrefix(targetUrl.Hostname(), "www.")
+// parse tokenizes the HTML body and collects every link of an enabled kind that points to an http(s) URL into the page. It's up to the
+// crawler to decide which of them are in scope.
+// Comments, <script> contents and anything inside a <template> are ignored, while <style> contents and style attributes
+// are searched for CSS url()s and @imports. Relative links are resolved against the
+// document's <base href> when one is present. Links that appear more than once are only kept the first time.
+// The page's canonical URL and meta robots noindex and nofollow are picked up along the way.
+func (f *Fetcher) parse(page *Page, htmlContent string, targetUrl *url.URL) {
+	doc := f.tokenize(htmlContent)
 
-	// Search based on the anchor tags in the HTML body.
-	re := regexp.MustCompile("<a.*?href=\"(.*?)\"")
-	matches := re.FindAllStringSubmatch(htmlContent, -1)
+	// The first <base href> applies to every link in the document, regardless of where it appears.
+	baseUrl := targetUrl
+	if doc.baseHref != "" {
+		if b, err := url.Parse(doc.baseHref); err == nil {
+			baseUrl = targetUrl.ResolveReference(b)
+		} else {
+			log.Printf("ignoring - unable to parse base href %s: %v\n", doc.baseHref, err)
+		}
+	}
 
+	if doc.canonical != "" {
+		if c, err := url.Parse(doc.canonical); err == nil {
+			page.Canonical = baseUrl.ResolveReference(c).String()
+		}
+	}
+
+	noIndex, noFollow := robotsDirectives(doc.robots)
+	page.NoIndex = page.NoIndex || noIndex
+	page.NoFollow = page.NoFollow || noFollow
+	page.Anchors = doc.ids
+	page.Links = resolveLinks(doc.links, baseUrl)
+}
+
+// parseStylesheet collects the @imports, fonts and images of an enabled kind in a stylesheet into the page. Their URLs
+// are relative to the stylesheet rather than to the page that uses it.
+func (f *Fetcher) parseStylesheet(page *Page, css string, targetUrl *url.URL) {
+	links := make([]Link, 0)
+	for _, link := range cssLinks(css) {
+		if f.kinds[link.Kind] {
+			link.Position = len(links) + 1
+			links = append(links, link)
+		}
+	}
+
+	page.Links = resolveLinks(links, targetUrl)
+}
+
+// resolveLinks resolves raw links against baseUrl, dropping any that aren't http(s) or that appear more than once.
+func resolveLinks(rawLinks []Link, baseUrl *url.URL) []Link {
 	foundUrls := make(map[string]bool)
-	for _, m := range matches {
-		foundUrl, err := url.Parse(m[1])
+	links := make([]Link, 0)
+	for _, a := range rawLinks {
+		ref, err := url.Parse(a.URL)
 		if err != nil {
-			log.Printf("skipping - unable to parse %s\n: %v", m[1], err)
+			log.Printf("skipping - unable to parse %s: %v\n", a.URL, err)
 			continue
 		}
 
-		// Cater to absolute and relative URLs.
-		if foundUrl.IsAbs() {
-			// Must match domain of the starting URL.
-			foundUrlHostname := strings.TrimPrefix(foundUrl.Hostname(), "www.")
-			if foundUrlHostname != targetHostname {
+		foundUrl := baseUrl.ResolveReference(ref)
+		if foundUrl.Scheme != "http" && foundUrl.Scheme != "https" {
+			continue
+		}
+
+		// Dedup matched URLs.
+		if foundUrls[foundUrl.String()] {
+			continue
+		}
+		foundUrls[foundUrl.String()] = true
+
+		a.URL = foundUrl.String()
+		links = append(links, a)
+	}
+	return links
+}
+
+// IsHtml reports whether a response's Content-Type is an HTML page.
+func IsHtml(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/html" || mediaType == "application/xhtml+xml")
+}
+
+// isStylesheet reports whether a response's Content-Type is CSS.
+func isStylesheet(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "text/css"
+}
+
+// document is what tokenize finds in an HTML page, before any URLs in it are resolved.
+type document struct {
+	links     []Link   // The raw URL, text, kind and position of every link of an enabled kind.
+	baseHref  string   // The first <base href>.
+	canonical string   // The first <link rel="canonical"> href.
+	robots    []string // The content of every <meta name="robots"> tag.
+	ids       []string // Every id attribute and <a name>, once each.
+}
+
+// tokenize walks through the HTML tokens and picks out the parts of the document that the crawler cares about.
+func (f *Fetcher) tokenize(htmlContent string) *document {
+	var (
+		doc           = &document{}
+		anchor        = -1 // The index of the anchor whose text is being read in doc.links, if any.
+		text          []string
+		position      int
+		templateDepth int
+		inStyle       bool
+	)
+
+	ids := make(map[string]bool)
+	addId := func(id string) {
+		if !ids[id] {
+			ids[id] = true
+			doc.ids = append(doc.ids, id)
+		}
+	}
+
+	// addLink adds a link of the given kind, unless its kind isn't enabled. It returns the link's index in doc.links.
+	addLink := func(kind LinkKind, u, linkText string) int {
+		if u == "" || !f.kinds[kind] {
+			return -1
+		}
+		position++
+		doc.links = append(doc.links, Link{URL: u, Text: linkText, Kind: kind, Position: position})
+		return len(doc.links) - 1
+	}
+
+	addCss := func(css string) {
+		for _, link := range cssLinks(css) {
+			addLink(link.Kind, link.URL, "")
+		}
+	}
+
+	addSrcset := func(t html.Token, linkText string) {
+		if srcset, ok := attr(t, "srcset"); ok {
+			for _, u := range srcsetUrls(srcset) {
+				addLink(KindImage, u, linkText)
+			}
+		}
+	}
+
+	closeAnchor := func() {
+		if anchor >= 0 {
+			doc.links[anchor].Text = strings.Join(strings.Fields(strings.Join(text, " ")), " ")
+		}
+		anchor, text = -1, nil
+	}
+
+	z := html.NewTokenizer(strings.NewReader(htmlContent))
+	for {
+		tt := z.Next()
+		switch tt {
+		case html.ErrorToken:
+			// io.EOF marks the end of the document; anything else is a malformed document that we parse on a best-effort basis.
+			if z.Err() != io.EOF {
+				log.Printf("stopped tokenizing early: %v\n", z.Err())
+			}
+			closeAnchor()
+			return doc
+		case html.TextToken:
+			if templateDepth > 0 {
 				continue
 			}
+			if inStyle {
+				addCss(string(z.Text()))
+			} else if anchor >= 0 {
+				text = append(text, string(z.Text()))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken, html.EndTagToken:
+			t := z.Token()
 
-			foundUrls[foundUrl.String()] = true
-		} else {
-			foundUrls[targetUrl.Scheme+"://"+targetUrl.Host+foundUrl.String()] = true
+			// <template> contents are inert until scripted, so they're never links a visitor can follow.
+			if t.Data == "template" {
+				if tt == html.StartTagToken {
+					templateDepth++
+				} else if tt == html.EndTagToken && templateDepth > 0 {
+					templateDepth--
+				}
+				continue
+			}
+
+			if templateDepth > 0 {
+				continue
+			}
+
+			if tt != html.EndTagToken {
+				if id, ok := attr(t, "id"); ok && id != "" {
+					addId(id)
+				}
+				if name, ok := attr(t, "name"); ok && name != "" && t.Data == "a" {
+					addId(name)
+				}
+			}
+
+			if tt == html.EndTagToken {
+				switch t.Data {
+				case "a":
+					closeAnchor()
+				case "style":
+					inStyle = false
+				}
+				continue
+			}
+
+			src, _ := attr(t, "src")
+			href, ok := attr(t, "href")
+
+			switch t.Data {
+			case "a":
+				// Anchors can't be nested, so a new one ends the previous one.
+				closeAnchor()
+				if ok {
+					anchor = addLink(KindNavigation, href, "")
+				}
+				if rel, _ := attr(t, "rel"); anchor >= 0 && hasToken(rel, "nofollow") {
+					doc.links[anchor].NoFollow = true
+				}
+			case "img":
+				// Images are often the only content of a link, in which case their alt text describes it.
+				alt, _ := attr(t, "alt")
+				if anchor >= 0 {
+					text = append(text, alt)
+				}
+				addLink(KindImage, src, alt)
+				addSrcset(t, alt)
+			case "script":
+				addLink(KindScript, src, "")
+			case "iframe":
+				addLink(KindIframe, src, "")
+			case "source":
+				addLink(KindMedia, src, "")
+				addSrcset(t, "")
+			case "video", "audio":
+				addLink(KindMedia, src, "")
+				poster, _ := attr(t, "poster")
+				addLink(KindImage, poster, "")
+			case "form":
+				// Forms that are posted can't be checked without the data they'd be posted with.
+				if method, _ := attr(t, "method"); method == "" || strings.EqualFold(method, "get") {
+					action, _ := attr(t, "action")
+					addLink(KindForm, action, "")
+				}
+			case "style":
+				inStyle = tt == html.StartTagToken
+			case "meta":
+				if name, _ := attr(t, "name"); strings.EqualFold(name, "robots") {
+					content, _ := attr(t, "content")
+					doc.robots = append(doc.robots, content)
+				}
+			case "base":
+				if ok && doc.baseHref == "" {
+					doc.baseHref = href
+				}
+			case "link":
+				rel, _ := attr(t, "rel")
+				if ok && doc.canonical == "" && strings.EqualFold(rel, "canonical") {
+					doc.canonical = href
+				}
+				as, _ := attr(t, "as")
+				if kind, isKind := linkRelKind(rel, as); isKind && ok {
+					addLink(kind, href, "")
+				}
+			}
+
+			// Inline styles come after the tag's own links, e.g. an anchor's background comes after the anchor.
+			if style, ok := attr(t, "style"); ok {
+				addCss(style)
+			}
+		}
+	}
+}
+
+// robotsDirectives reports whether any of the robots directives, from meta robots tags or X-Robots-Tag headers, ask for the
+// page to be kept out of search indexes (noindex) and for its links not to be followed (nofollow). "none" asks for both.
+// Directives aimed at a specific bot, e.g. "googlebot: noindex", are taken as aimed at everyone.
+func robotsDirectives(directives []string) (noIndex bool, noFollow bool) {
+	for _, d := range directives {
+		for _, v := range strings.Split(d, ",") {
+			if i := strings.LastIndex(v, ":"); i >= 0 {
+				v = v[i+1:]
+			}
+			switch strings.ToLower(strings.TrimSpace(v)) {
+			case "noindex":
+				noIndex = true
+			case "nofollow":
+				noFollow = true
+			case "none":
+				noIndex, noFollow = true, true
+			}
 		}
 	}
+	return noIndex, noFollow
+}
 
-	// Dedup matched URLs.
-	uniqueUrls := make([]string, 0)
-	for u := range foundUrls {
-		uniqueUrls = append(uniqueUrls, u)
+// hasToken reports whether a space-separated list of tokens, e.g. a rel attribute, contains token, ignoring case.
+func hasToken(tokens string, token string) bool {
+	for _, t := range strings.Fields(tokens) {
+		if strings.EqualFold(t, token) {
+			return true
+		}
 	}
+	return false
+}
 
-	return uniqueUrls
+// attr returns the whitespace-trimmed value of the given attribute key.
+func attr(t html.Token, key string) (string, bool) {
+	for _, a := range t.Attr {
+		if a.Key == key {
+			return strings.TrimSpace(a.Val), true
+		}
+	}
+	return "", false
 }
@@ -1,13 +1,26 @@
 package fetcher
 
 import (
+	"context"
 	"fmt"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
 )
 
+func linkUrls(links []Link) []string {
+	urls := make([]string, len(links))
+	for i, l := range links {
+		urls[i] = l.URL
+	}
+	return urls
+}
+
 func TestFetcher_Fetch(t *testing.T) {
 	t.Run("when the HTML page has urls", func(t *testing.T) {
 		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -30,12 +43,17 @@ func TestFetcher_Fetch(t *testing.T) {
 		defer testServer.Close()
 
 		f := NewFetcher()
-		urls, err := f.Fetch(testServer.URL)
+		page, err := f.Fetch(context.Background(), testServer.URL)
 		assert.Nil(t, err)
 		assert.ElementsMatch(t, []string{
 			fmt.Sprintf("%s/about/", testServer.URL),
 			fmt.Sprintf("%s/settings", testServer.URL),
-		}, urls)
+			"https://monzo.com/about/",
+			"https://google.com",
+		}, linkUrls(page.Links))
+		assert.Equal(t, http.StatusOK, page.StatusCode)
+		assert.Equal(t, "text/html; charset=utf-8", page.ContentType)
+		assert.Positive(t, page.Size)
 	})
 
 	t.Run("when the HTML page has no urls", func(t *testing.T) {
@@ -55,22 +73,543 @@ func TestFetcher_Fetch(t *testing.T) {
 		defer testServer.Close()
 
 		f := NewFetcher()
-		urls, err := f.Fetch(testServer.URL)
+		page, err := f.Fetch(context.Background(), testServer.URL)
 		assert.Nil(t, err)
-		assert.Empty(t, urls)
+		assert.Empty(t, page.Links)
+	})
+
+	t.Run("when the HTML page has indexing signals", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Last-Modified", "Wed, 21 Oct 2015 07:28:00 GMT")
+			if r.URL.Path == "/private" {
+				w.Header().Set("X-Robots-Tag", "googlebot: noindex, nofollow")
+			}
+			fmt.Fprint(w, `<head><link rel="canonical" href="/about/"></head><a href="/about/">About</a>`)
+		}))
+		defer testServer.Close()
+
+		f := NewFetcher()
+		page, err := f.Fetch(context.Background(), testServer.URL+"/about/?ref=home")
+		require.NoError(t, err)
+		assert.Equal(t, time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC), page.LastModified)
+		assert.Equal(t, testServer.URL+"/about/", page.Canonical)
+		assert.False(t, page.NoIndex)
+
+		page, err = f.Fetch(context.Background(), testServer.URL+"/private")
+		require.NoError(t, err)
+		assert.True(t, page.NoIndex)
+		assert.True(t, page.NoFollow)
+	})
+
+	t.Run("when the HTML page has anchors", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `
+				<h1 id="intro">Intro</h1>
+				<a name="install">Install</a>
+				<div id="install"><a href="#intro" id="back">Back</a></div>
+				<input name="q">
+				<template><p id="hidden"></p></template>
+				<section id="">Empty</section>
+			`)
+		}))
+		defer testServer.Close()
+
+		page, err := NewFetcher().Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"intro", "install", "back"}, page.Anchors)
+	})
+
+	t.Run("when the page isn't HTML", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"html": "<a href=\\"/about/\\">About</a>"}`)
+		}))
+		defer testServer.Close()
+
+		page, err := NewFetcher().Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+		assert.Empty(t, page.Links, "only HTML and CSS should be searched for links")
+		assert.Equal(t, "application/json", page.ContentType)
+		assert.Positive(t, page.Size)
+	})
+
+	t.Run("when the page is XHTML", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xhtml+xml")
+			fmt.Fprint(w, `<html xmlns="http://www.w3.org/1999/xhtml"><body><a href="/about/">About</a></body></html>`)
+		}))
+		defer testServer.Close()
+
+		page, err := NewFetcher().Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{testServer.URL + "/about/"}, linkUrls(page.Links))
+	})
+
+	t.Run("when the HTML page responds with an error status", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "120")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			fmt.Fprint(w, `<a href="/about/">About</a>`)
+		}))
+		defer testServer.Close()
+
+		f := NewFetcher()
+		page, err := f.Fetch(context.Background(), testServer.URL+"/maintenance")
+
+		var statusErr *HTTPStatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusServiceUnavailable, statusErr.StatusCode)
+		assert.Equal(t, testServer.URL+"/maintenance", statusErr.URL)
+		assert.Equal(t, "120", statusErr.Header.Get("Retry-After"))
+		assert.Equal(t, ErrorRetryable, Classify(err))
+		assert.Nil(t, page)
+	})
+
+	t.Run("when the HTML page redirects to a missing page", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.Handle("/old", http.RedirectHandler("/new", http.StatusMovedPermanently))
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		f := NewFetcher()
+		page, err := f.Fetch(context.Background(), testServer.URL+"/old")
+
+		var statusErr *HTTPStatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+		assert.Equal(t, testServer.URL+"/new", statusErr.URL)
+		assert.Equal(t, ErrorPermanent, Classify(err))
+		assert.Nil(t, page)
 	})
 
 	t.Run("when unable to access HTML page", func(t *testing.T) {
 		f := NewFetcher()
-		urls, err := f.Fetch("https://localhost.org/")
+		page, err := f.Fetch(context.Background(), "https://localhost.org/")
 		assert.ErrorContains(t, err, "no such host")
-		assert.Empty(t, urls)
+		assert.Equal(t, ErrorPermanent, Classify(err))
+		assert.Nil(t, page)
 	})
 
 	t.Run("when target URL is invalid", func(t *testing.T) {
 		f := NewFetcher()
-		urls, err := f.Fetch("MALFOMRED_URL.")
+		page, err := f.Fetch(context.Background(), "MALFOMRED_URL.")
 		assert.ErrorContains(t, err, "unsupported protocol scheme")
-		assert.Empty(t, urls)
+		assert.Nil(t, page)
 	})
 }
+
+func TestFetcher_Options(t *testing.T) {
+	t.Run("sends the user agent and extra headers", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "webcrawler-go/1.0", r.Header.Get("User-Agent"))
+			assert.Equal(t, "en-GB", r.Header.Get("Accept-Language"))
+			fmt.Fprint(w, `<a href="/about/">About</a>`)
+		}))
+		defer testServer.Close()
+
+		f := NewFetcher(
+			WithUserAgent("webcrawler-go/1.0"),
+			WithHeaders(map[string]string{"Accept-Language": "en-GB", "User-Agent": "overridden"}),
+		)
+		page, err := f.Fetch(context.Background(), testServer.URL)
+		assert.Nil(t, err)
+		assert.Len(t, page.Links, 1)
+	})
+
+	t.Run("gives up on slow pages", func(t *testing.T) {
+		done := make(chan struct{})
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer testServer.Close()
+		defer close(done)
+
+		f := NewFetcher(WithTimeout(50 * time.Millisecond))
+		start := time.Now()
+		page, err := f.Fetch(context.Background(), testServer.URL)
+		assert.ErrorContains(t, err, "Client.Timeout exceeded")
+		assert.Equal(t, ErrorRetryable, Classify(err))
+		assert.Nil(t, page)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("gives up on pages that are slow to respond with headers", func(t *testing.T) {
+		done := make(chan struct{})
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer testServer.Close()
+		defer close(done)
+
+		f := NewFetcher(WithResponseHeaderTimeout(50 * time.Millisecond))
+		page, err := f.Fetch(context.Background(), testServer.URL)
+		assert.ErrorContains(t, err, "timeout awaiting response headers")
+		assert.Nil(t, page)
+	})
+
+	t.Run("stops fetching when the context is done", func(t *testing.T) {
+		done := make(chan struct{})
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-done
+		}))
+		defer testServer.Close()
+		defer close(done)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		time.AfterFunc(50*time.Millisecond, cancel)
+
+		f := NewFetcher()
+		page, err := f.Fetch(ctx, testServer.URL)
+		assert.ErrorIs(t, err, context.Canceled)
+		assert.Nil(t, page)
+	})
+
+	t.Run("routes requests through the proxy", func(t *testing.T) {
+		proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Proxied requests carry the absolute URL of the target.
+			assert.Equal(t, "http://monzo.com/", r.URL.String())
+			fmt.Fprint(w, `<a href="/about/">About</a>`)
+		}))
+		defer proxyServer.Close()
+
+		proxyUrl, err := url.Parse(proxyServer.URL)
+		require.NoError(t, err)
+
+		f := NewFetcher(WithProxy(proxyUrl))
+		page, err := f.Fetch(context.Background(), "http://monzo.com/")
+		assert.Nil(t, err)
+		assert.Equal(t, []string{"http://monzo.com/about/"}, linkUrls(page.Links))
+	})
+
+	t.Run("only reads up to the max body size", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<a href="/about/">About</a>`+strings.Repeat(" ", 1000)+`<a href="/hidden/">Hidden</a>`)
+		}))
+		defer testServer.Close()
+
+		page, err := NewFetcher(WithMaxBodySize(100)).Fetch(context.Background(), testServer.URL)
+		require.NoError(t, err)
+		assert.Equal(t, []string{testServer.URL + "/about/"}, linkUrls(page.Links))
+		assert.Equal(t, int64(100), page.Size)
+	})
+
+	t.Run("tunes the connection pool", func(t *testing.T) {
+		f := NewFetcher(WithMaxIdleConnsPerHost(42), WithTLSHandshakeTimeout(time.Second))
+		transport := f.Client().Transport.(*http.Transport)
+		assert.Equal(t, 42, transport.MaxIdleConnsPerHost)
+		assert.Equal(t, time.Second, transport.TLSHandshakeTimeout)
+	})
+}
+
+func TestFetcher_parse(t *testing.T) {
+	targetUrl, err := url.Parse("https://monzo.com/blog/latest/")
+	require.NoError(t, err)
+
+	// A corpus of HTML snippets that the crawler has historically mis-parsed.
+	fixtures := []struct {
+		name     string
+		html     string
+		expected []string
+	}{
+		{
+			name:     "double-quoted href",
+			html:     `<a href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "single-quoted href",
+			html:     `<a href='/about/'>About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "unquoted href",
+			html:     `<a href=/about/>About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "attributes before href",
+			html:     `<a class="nav" data-track='{"a":1}' id=x href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "uppercase tag and attribute",
+			html:     `<A HREF="/about/">About</A>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name: "href spanning multiple lines",
+			html: `<a
+	class="nav"
+	href="/about/"
+>About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "whitespace around href value",
+			html:     `<a href="  /about/  ">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "entity-encoded href",
+			html:     `<a href="/search?q=cards&amp;page=2">Search</a>`,
+			expected: []string{"https://monzo.com/search?q=cards&page=2"},
+		},
+		{
+			name:     "path-relative href",
+			html:     `<a href="next/">Next</a><a href="../archive/">Archive</a>`,
+			expected: []string{"https://monzo.com/blog/latest/next/", "https://monzo.com/blog/archive/"},
+		},
+		{
+			name:     "protocol-relative href",
+			html:     `<a href="//monzo.com/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "www subdomain of the starting URL",
+			html:     `<a href="https://www.monzo.com/about/">About</a>`,
+			expected: []string{"https://www.monzo.com/about/"},
+		},
+		{
+			name:     "links in comments",
+			html:     `<!-- <a href="/hidden/">Hidden</a> --><a href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "links in script blocks",
+			html:     `<script>document.write('<a href="/hidden/">Hidden</a>');</script><a href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "links in style blocks",
+			html:     `<style>/* <a href="/hidden/"> */</style><a href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "links in templates",
+			html:     `<template><div><template><a href="/nested/">Nested</a></template><a href="/hidden/">Hidden</a></div></template><a href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "base href",
+			html:     `<head><base href="https://monzo.com/docs/"></head><a href="install">Install</a><a href="/about/">About</a>`,
+			expected: []string{"https://monzo.com/docs/install", "https://monzo.com/about/"},
+		},
+		{
+			name:     "relative base href",
+			html:     `<head><base href="/docs/"></head><a href="install">Install</a>`,
+			expected: []string{"https://monzo.com/docs/install"},
+		},
+		{
+			name:     "base href declared after the links",
+			html:     `<a href="install">Install</a><base href="/docs/"><base href="/ignored/">`,
+			expected: []string{"https://monzo.com/docs/install"},
+		},
+		{
+			name:     "base href pointing to another domain",
+			html:     `<base href="https://google.com/"><a href="about/">About</a>`,
+			expected: []string{"https://google.com/about/"},
+		},
+		{
+			name:     "non-http schemes",
+			html:     `<a href="mailto:help@monzo.com">Mail</a><a href="tel:+44">Call</a><a href="javascript:void(0)">JS</a><a href="ftp://monzo.com/file">FTP</a>`,
+			expected: []string{},
+		},
+		{
+			name:     "empty and missing href",
+			html:     `<a href="">Empty</a><a name="top">Top</a><a href>Bare</a>`,
+			expected: []string{},
+		},
+		{
+			name:     "non-anchor tags with href",
+			html:     `<link rel="stylesheet" href="/main.css"><area href="/map/">`,
+			expected: []string{},
+		},
+		{
+			name:     "other domains",
+			html:     `<a href="https://google.com/">Google</a><a href="https://monzo.com.evil.com/">Evil</a>`,
+			expected: []string{"https://google.com/", "https://monzo.com.evil.com/"},
+		},
+		{
+			name:     "duplicate links",
+			html:     `<a href="/about/">About</a><a href='/about/'>About us</a><a href="https://monzo.com/about/">About</a>`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+		{
+			name:     "unterminated markup",
+			html:     `<div><a href="/about/">About<p><a href="/help/"`,
+			expected: []string{"https://monzo.com/about/"},
+		},
+	}
+
+	f := NewFetcher()
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			page := &Page{}
+			f.parse(page, fixture.html, targetUrl)
+			urls := linkUrls(page.Links)
+			assert.ElementsMatch(t, fixture.expected, urls)
+		})
+	}
+}
+
+func TestFetcher_parse_anchors(t *testing.T) {
+	targetUrl, err := url.Parse("https://monzo.com/")
+	require.NoError(t, err)
+
+	f := NewFetcher()
+	page := &Page{}
+	f.parse(page, `<nav>
+	<a href="/about/">  About
+		<strong>us</strong> </a>
+	<a href="/help/"><img src="/help.png" alt="Get help"></a>
+	<a href="mailto:help@monzo.com">Mail</a>
+	<a href="/blog/">Blog<a href="/about/">About again</a>
+	<template><a href="/hidden/">Hidden</a></template>
+	<a href="/careers/">Careers
+</nav>`, targetUrl)
+
+	assert.Equal(t, []Link{
+		{URL: "https://monzo.com/about/", Text: "About us", Kind: KindNavigation, Position: 1},
+		{URL: "https://monzo.com/help/", Text: "Get help", Kind: KindNavigation, Position: 2},
+		{URL: "https://monzo.com/blog/", Text: "Blog", Kind: KindNavigation, Position: 4},
+		{URL: "https://monzo.com/careers/", Text: "Careers", Kind: KindNavigation, Position: 6},
+	}, page.Links)
+}
+
+func TestFetcher_parse_kinds(t *testing.T) {
+	targetUrl, err := url.Parse("https://monzo.com/")
+	require.NoError(t, err)
+
+	html := `<head>
+	<link rel="stylesheet" href="/main.css">
+	<link rel="icon" href="/favicon.png">
+	<link rel="preload" href="/font.woff2" as="font">
+	<link rel="preconnect" href="https://cdn.monzo.com/">
+	<link rel="modulepreload" href="/app.mjs">
+	<script src="/app.js"></script>
+</head>
+<body>
+	<a href="/about/"><img src="/about.png" srcset="/about-2x.png 2x, /about-3x.png 3x" alt="About"></a>
+	<picture><source srcset="/hero.webp" type="image/webp"></picture>
+	<video src="/intro.mp4" poster="/intro.jpg"><source src="/intro.webm"></video>
+	<audio src="/podcast.mp3"></audio>
+	<iframe src="https://www.youtube.com/embed/x"></iframe>
+	<form action="/search"></form>
+	<form action="/login" method="post"></form>
+</body>`
+
+	t.Run("picks up every kind of link that's enabled", func(t *testing.T) {
+		f := NewFetcher(WithLinkKinds(LinkKinds...))
+		page := &Page{}
+		f.parse(page, html, targetUrl)
+
+		assert.Equal(t, []Link{
+			{URL: "https://monzo.com/main.css", Kind: KindStylesheet, Position: 1},
+			{URL: "https://monzo.com/favicon.png", Kind: KindImage, Position: 2},
+			{URL: "https://monzo.com/font.woff2", Kind: KindFont, Position: 3},
+			{URL: "https://monzo.com/app.mjs", Kind: KindScript, Position: 4},
+			{URL: "https://monzo.com/app.js", Kind: KindScript, Position: 5},
+			{URL: "https://monzo.com/about/", Text: "About", Kind: KindNavigation, Position: 6},
+			{URL: "https://monzo.com/about.png", Text: "About", Kind: KindImage, Position: 7},
+			{URL: "https://monzo.com/about-2x.png", Text: "About", Kind: KindImage, Position: 8},
+			{URL: "https://monzo.com/about-3x.png", Text: "About", Kind: KindImage, Position: 9},
+			{URL: "https://monzo.com/hero.webp", Kind: KindImage, Position: 10},
+			{URL: "https://monzo.com/intro.mp4", Kind: KindMedia, Position: 11},
+			{URL: "https://monzo.com/intro.jpg", Kind: KindImage, Position: 12},
+			{URL: "https://monzo.com/intro.webm", Kind: KindMedia, Position: 13},
+			{URL: "https://monzo.com/podcast.mp3", Kind: KindMedia, Position: 14},
+			{URL: "https://www.youtube.com/embed/x", Kind: KindIframe, Position: 15},
+			{URL: "https://monzo.com/search", Kind: KindForm, Position: 16},
+		}, page.Links)
+	})
+
+	t.Run("leaves out kinds that aren't enabled", func(t *testing.T) {
+		f := NewFetcher(WithLinkKinds(KindStylesheet, KindIframe))
+		page := &Page{}
+		f.parse(page, html, targetUrl)
+
+		assert.Equal(t, []Link{
+			{URL: "https://monzo.com/main.css", Kind: KindStylesheet, Position: 1},
+			{URL: "https://www.youtube.com/embed/x", Kind: KindIframe, Position: 2},
+		}, page.Links)
+	})
+}
+
+func TestFetcher_parse_indexing(t *testing.T) {
+	targetUrl, err := url.Parse("https://monzo.com/blog/")
+	require.NoError(t, err)
+
+	fixtures := []struct {
+		name      string
+		html      string
+		canonical string
+		noIndex   bool
+		noFollow  bool
+	}{
+		{
+			name:      "relative canonical",
+			html:      `<head><link rel="canonical" href="latest/"><link rel="canonical" href="/ignored/"></head>`,
+			canonical: "https://monzo.com/blog/latest/",
+		},
+		{
+			name:      "canonical resolved against the base href",
+			html:      `<head><base href="https://www.monzo.com/"><link REL="Canonical" href="blog/"></head>`,
+			canonical: "https://www.monzo.com/blog/",
+		},
+		{
+			name:    "meta robots noindex",
+			html:    `<head><meta name="robots" content="NOINDEX, follow"></head>`,
+			noIndex: true,
+		},
+		{
+			name:     "meta robots none",
+			html:     `<head><meta name="Robots" content="none"></head>`,
+			noIndex:  true,
+			noFollow: true,
+		},
+		{
+			name:     "meta robots that allows indexing",
+			html:     `<head><meta name="robots" content="index, nofollow"><meta name="description" content="noindex"></head>`,
+			noFollow: true,
+		},
+		{
+			name:     "meta robots nofollow aimed at a bot",
+			html:     `<head><meta name="robots" content="googlebot: NoFollow"></head>`,
+			noFollow: true,
+		},
+		{
+			name: "no signals",
+			html: `<a href="/about/">About</a>`,
+		},
+	}
+
+	f := NewFetcher()
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			page := &Page{}
+			f.parse(page, fixture.html, targetUrl)
+			assert.Equal(t, fixture.canonical, page.Canonical)
+			assert.Equal(t, fixture.noIndex, page.NoIndex)
+			assert.Equal(t, fixture.noFollow, page.NoFollow)
+		})
+	}
+}
+
+func TestFetcher_parse_nofollow(t *testing.T) {
+	targetUrl, err := url.Parse("https://monzo.com/")
+	require.NoError(t, err)
+
+	f := NewFetcher()
+	page := &Page{}
+	f.parse(page, `<a href="/about/">About</a>
+<a href="/ads/" rel="sponsored NoFollow">Ad</a>
+<a href="/blog/" rel="noopener">Blog</a>`, targetUrl)
+
+	assert.Equal(t, []Link{
+		{URL: "https://monzo.com/about/", Text: "About", Kind: KindNavigation, Position: 1},
+		{URL: "https://monzo.com/ads/", Text: "Ad", Kind: KindNavigation, Position: 2, NoFollow: true},
+		{URL: "https://monzo.com/blog/", Text: "Blog", Kind: KindNavigation, Position: 3},
+	}, page.Links)
+	assert.False(t, page.NoFollow)
+}
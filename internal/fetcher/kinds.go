@@ -0,0 +1,120 @@
+package fetcher
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// LinkKind is what a link on a page is used for, which depends on the tag and attribute it was found in.
+type LinkKind string
+
+const (
+	KindNavigation LinkKind = "navigation" // <a href>.
+	KindStylesheet LinkKind = "stylesheet" // <link rel="stylesheet">, <link rel="preload" as="style"> and CSS @imports.
+	KindScript     LinkKind = "script"     // <script src>, <link rel="modulepreload"> and <link rel="preload" as="script">.
+	KindImage      LinkKind = "image"      // <img src/srcset>, <source srcset>, <video poster>, icons, <link rel="preload" as="image"> and CSS url()s.
+	KindFont       LinkKind = "font"       // url()s in CSS @font-face rules and <link rel="preload" as="font">.
+	KindMedia      LinkKind = "media"      // <video src>, <audio src> and <source src>.
+	KindIframe     LinkKind = "iframe"     // <iframe src>.
+	KindForm       LinkKind = "form"       // <form action>, for forms that are submitted with a GET.
+)
+
+// LinkKinds lists every kind of link, in the order they're documented in.
+var LinkKinds = []LinkKind{KindNavigation, KindStylesheet, KindScript, KindImage, KindFont, KindMedia, KindIframe, KindForm}
+
+// ParseLinkKinds turns the names of link kinds, e.g. from the config, into LinkKinds.
+func ParseLinkKinds(names []string) ([]LinkKind, error) {
+	kinds := make([]LinkKind, 0, len(names))
+	for _, name := range names {
+		kind := LinkKind(strings.ToLower(name))
+		if !kind.valid() {
+			return nil, fmt.Errorf("unknown link kind %q", name)
+		}
+		kinds = append(kinds, kind)
+	}
+	return kinds, nil
+}
+
+func (k LinkKind) valid() bool {
+	for _, kind := range LinkKinds {
+		if k == kind {
+			return true
+		}
+	}
+	return false
+}
+
+// linkRelKind works out the kind of a <link> tag from its rel and as attributes. Links that don't point to something that
+// the page loads, e.g. rel="preconnect" or rel="alternate", have no kind.
+func linkRelKind(rel, as string) (LinkKind, bool) {
+	for _, r := range strings.Fields(strings.ToLower(rel)) {
+		switch r {
+		case "stylesheet":
+			return KindStylesheet, true
+		case "icon", "apple-touch-icon", "apple-touch-icon-precomposed", "mask-icon":
+			return KindImage, true
+		case "modulepreload":
+			return KindScript, true
+		case "preload":
+			switch strings.ToLower(as) {
+			case "style":
+				return KindStylesheet, true
+			case "script":
+				return KindScript, true
+			case "image":
+				return KindImage, true
+			case "font":
+				return KindFont, true
+			case "audio", "video", "track":
+				return KindMedia, true
+			}
+		}
+	}
+	return "", false
+}
+
+// srcsetUrls returns the URLs of the image candidates in a srcset attribute, e.g. "a.png 1x, b.png 2x". URLs can contain
+// commas, so candidates are split on the comma that ends their descriptors rather than on every comma.
+func srcsetUrls(srcset string) []string {
+	urls := make([]string, 0)
+	for rest := srcset; ; {
+		rest = strings.TrimLeftFunc(rest, func(r rune) bool { return unicode.IsSpace(r) || r == ',' })
+		if rest == "" {
+			return urls
+		}
+
+		end := strings.IndexFunc(rest, unicode.IsSpace)
+		if end < 0 {
+			end = len(rest)
+		}
+		u := rest[:end]
+		rest = rest[end:]
+
+		// A URL that ends with a comma has no descriptors. Otherwise, they run up to the next comma outside of parentheses.
+		if trimmed := strings.TrimRight(u, ","); trimmed != u {
+			u = trimmed
+		} else {
+			depth := 0
+			end = strings.IndexFunc(rest, func(r rune) bool {
+				switch r {
+				case '(':
+					depth++
+				case ')':
+					depth--
+				case ',':
+					return depth <= 0
+				}
+				return false
+			})
+			if end < 0 {
+				end = len(rest)
+			}
+			rest = rest[end:]
+		}
+
+		if u != "" {
+			urls = append(urls, u)
+		}
+	}
+}
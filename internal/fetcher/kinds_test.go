@@ -0,0 +1,40 @@
+package fetcher
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestParseLinkKinds(t *testing.T) {
+	t.Run("parses known kinds", func(t *testing.T) {
+		kinds, err := ParseLinkKinds([]string{"navigation", "Image", "form"})
+		assert.NoError(t, err)
+		assert.Equal(t, []LinkKind{KindNavigation, KindImage, KindForm}, kinds)
+	})
+
+	t.Run("rejects unknown kinds", func(t *testing.T) {
+		_, err := ParseLinkKinds([]string{"navigation", "video"})
+		assert.EqualError(t, err, `unknown link kind "video"`)
+	})
+}
+
+func Test_srcsetUrls(t *testing.T) {
+	fixtures := []struct {
+		name     string
+		srcset   string
+		expected []string
+	}{
+		{name: "single url", srcset: "/a.png", expected: []string{"/a.png"}},
+		{name: "density descriptors", srcset: "/a.png 1x, /b.png 2x", expected: []string{"/a.png", "/b.png"}},
+		{name: "width descriptors and odd whitespace", srcset: "\n\t/a.png  480w ,/b.png 800w,", expected: []string{"/a.png", "/b.png"}},
+		{name: "commas in urls", srcset: "/img?w=1,2 1x, /img?w=3,4 2x", expected: []string{"/img?w=1,2", "/img?w=3,4"}},
+		{name: "candidates without descriptors", srcset: "/a.png, /b.png 2x", expected: []string{"/a.png", "/b.png"}},
+		{name: "empty", srcset: " , ", expected: []string{}},
+	}
+
+	for _, fixture := range fixtures {
+		t.Run(fixture.name, func(t *testing.T) {
+			assert.Equal(t, fixture.expected, srcsetUrls(fixture.srcset))
+		})
+	}
+}
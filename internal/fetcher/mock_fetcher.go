@@ -1,6 +1,9 @@
 package fetcher
 
-import "fmt"
+import (
+	"context"
+	"fmt"
+)
 
 type MockFetcher map[string]*fakeResult
 
@@ -14,6 +17,7 @@ func NewMockFetcher() MockFetcher {
 			[]string{
 				"https://monzo.com/current-account/",
 				"https://monzo.com/monzo-plus/",
+				"https://twitter.com/monzo",
 			},
 		},
 		"https://monzo.com/current-account/": &fakeResult{
@@ -42,9 +46,13 @@ func NewMockFetcher() MockFetcher {
 	}
 }
 
-func (f MockFetcher) Fetch(targetUrl string) ([]string, error) {
+func (f MockFetcher) Fetch(ctx context.Context, targetUrl string) (*Page, error) {
 	if res, ok := f[targetUrl]; ok {
-		return res.urls, nil
+		links := make([]Link, len(res.urls))
+		for i, u := range res.urls {
+			links[i] = Link{URL: u, Kind: KindNavigation, Position: i + 1}
+		}
+		return &Page{URL: targetUrl, StatusCode: 200, ContentType: "text/html; charset=utf-8", Links: links}, nil
 	}
 
 	return nil, fmt.Errorf("cannot parse any urls from: %s", targetUrl)
@@ -0,0 +1,27 @@
+package fetcher
+
+import (
+	"context"
+	"webcrawler-go/internal/ratelimit"
+)
+
+// RateLimitedFetcher waits for the limiter before every fetch. A single instance should be shared by all crawl workers
+// so that they draw from the same per-host budget.
+type RateLimitedFetcher struct {
+	fetcher IFetcher
+	limiter ratelimit.ILimiter
+}
+
+func NewRateLimitedFetcher(fetcher IFetcher, limiter ratelimit.ILimiter) *RateLimitedFetcher {
+	return &RateLimitedFetcher{
+		fetcher: fetcher,
+		limiter: limiter,
+	}
+}
+
+func (f *RateLimitedFetcher) Fetch(ctx context.Context, targetUrl string) (*Page, error) {
+	if err := f.limiter.Wait(ctx, targetUrl); err != nil {
+		return nil, err
+	}
+	return f.fetcher.Fetch(ctx, targetUrl)
+}
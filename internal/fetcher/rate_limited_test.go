@@ -0,0 +1,32 @@
+package fetcher
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type recordingLimiter struct {
+	waited []string
+}
+
+func (l *recordingLimiter) Wait(ctx context.Context, targetUrl string) error {
+	l.waited = append(l.waited, targetUrl)
+	return ctx.Err()
+}
+
+func TestRateLimitedFetcher_Fetch(t *testing.T) {
+	t.Run("waits for the limiter before fetching", func(t *testing.T) {
+		l := &recordingLimiter{}
+		f := NewRateLimitedFetcher(NewMockFetcher(), l)
+
+		page, err := f.Fetch(context.Background(), "https://monzo.com/")
+		assert.Nil(t, err)
+		assert.Len(t, page.Links, 3)
+
+		_, err = f.Fetch(context.Background(), "http://dummysite.com/")
+		assert.Error(t, err)
+
+		assert.Equal(t, []string{"https://monzo.com/", "http://dummysite.com/"}, l.waited)
+	})
+}
@@ -0,0 +1,64 @@
+package fetcher
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// defaultMaxRedirects is the no. of redirects the http package follows by default.
+const defaultMaxRedirects = 10
+
+// Redirect is a single hop in a redirect chain.
+type Redirect struct {
+	URL        string `json:"url"`        // The URL that redirected.
+	StatusCode int    `json:"statusCode"` // The redirect's status code, e.g. 301.
+}
+
+// RedirectError is returned when following a page's redirects would go on for too long, or in circles.
+type RedirectError struct {
+	Redirects []Redirect // The redirects that were followed before giving up.
+	Next      string     // The URL that the last redirect pointed to.
+	Loop      bool       // Whether Next was already part of the chain.
+}
+
+func (e *RedirectError) Error() string {
+	urls := make([]string, 0, len(e.Redirects)+1)
+	for _, r := range e.Redirects {
+		urls = append(urls, r.URL)
+	}
+	urls = append(urls, e.Next)
+
+	if e.Loop {
+		return fmt.Sprintf("redirect loop: %s", strings.Join(urls, " -> "))
+	}
+	return fmt.Sprintf("stopped after %d redirects: %s", len(e.Redirects), strings.Join(urls, " -> "))
+}
+
+// checkRedirect stops following redirects once there have been more than max of them, or when they loop back to a URL
+// that's already part of the chain.
+func checkRedirect(max int) func(req *http.Request, via []*http.Request) error {
+	return func(req *http.Request, via []*http.Request) error {
+		chain := redirects(req)
+		for _, r := range chain {
+			if r.URL == req.URL.String() {
+				return &RedirectError{Redirects: chain, Next: req.URL.String(), Loop: true}
+			}
+		}
+
+		if len(chain) > max {
+			return &RedirectError{Redirects: chain, Next: req.URL.String()}
+		}
+
+		return nil
+	}
+}
+
+// redirects returns the redirect chain that led to req, in the order the redirects were followed.
+func redirects(req *http.Request) []Redirect {
+	var chain []Redirect
+	for r := req; r.Response != nil && r.Response.Request != nil; r = r.Response.Request {
+		chain = append([]Redirect{{URL: r.Response.Request.URL.String(), StatusCode: r.Response.StatusCode}}, chain...)
+	}
+	return chain
+}
@@ -0,0 +1,100 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// redirectServer redirects /1 to /2 and so on up to /hops, which serves a page with a relative link.
+// /loop-a and /loop-b redirect to each other.
+func redirectServer(hops int) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/%d", &n)
+
+		switch {
+		case r.URL.Path == "/loop-a":
+			http.Redirect(w, r, "/loop-b", http.StatusFound)
+		case r.URL.Path == "/loop-b":
+			http.Redirect(w, r, "/loop-a", http.StatusMovedPermanently)
+		case n > 0 && n < hops:
+			http.Redirect(w, r, fmt.Sprintf("/%d", n+1), http.StatusMovedPermanently)
+		default:
+			fmt.Fprint(w, `<a href="next/">Next</a>`)
+		}
+	}))
+}
+
+func TestFetcher_redirects(t *testing.T) {
+	t.Run("records the redirect chain and the final URL", func(t *testing.T) {
+		testServer := redirectServer(3)
+		defer testServer.Close()
+
+		page, err := NewFetcher().Fetch(context.Background(), testServer.URL+"/1")
+		require.NoError(t, err)
+
+		assert.Equal(t, testServer.URL+"/3", page.URL)
+		assert.Equal(t, []Redirect{
+			{URL: testServer.URL + "/1", StatusCode: http.StatusMovedPermanently},
+			{URL: testServer.URL + "/2", StatusCode: http.StatusMovedPermanently},
+		}, page.Redirects)
+		assert.Equal(t, []string{testServer.URL + "/next/"}, linkUrls(page.Links), "links should be relative to the final URL")
+	})
+
+	t.Run("doesn't record a chain when there are no redirects", func(t *testing.T) {
+		testServer := redirectServer(1)
+		defer testServer.Close()
+
+		page, err := NewFetcher().Fetch(context.Background(), testServer.URL+"/1")
+		require.NoError(t, err)
+
+		assert.Equal(t, testServer.URL+"/1", page.URL)
+		assert.Empty(t, page.Redirects)
+	})
+
+	t.Run("stops after the max no. of redirects", func(t *testing.T) {
+		testServer := redirectServer(5)
+		defer testServer.Close()
+
+		_, err := NewFetcher(WithMaxRedirects(2)).Fetch(context.Background(), testServer.URL+"/1")
+
+		var redirectErr *RedirectError
+		require.ErrorAs(t, err, &redirectErr)
+		assert.False(t, redirectErr.Loop)
+		assert.Len(t, redirectErr.Redirects, 3)
+		assert.Equal(t, testServer.URL+"/4", redirectErr.Next)
+		assert.Equal(t, ErrorPermanent, Classify(err))
+	})
+
+	t.Run("detects redirect loops", func(t *testing.T) {
+		testServer := redirectServer(0)
+		defer testServer.Close()
+
+		_, err := NewFetcher().Fetch(context.Background(), testServer.URL+"/loop-a")
+
+		var redirectErr *RedirectError
+		require.ErrorAs(t, err, &redirectErr)
+		assert.True(t, redirectErr.Loop)
+		assert.Equal(t, []Redirect{
+			{URL: testServer.URL + "/loop-a", StatusCode: http.StatusFound},
+			{URL: testServer.URL + "/loop-b", StatusCode: http.StatusMovedPermanently},
+		}, redirectErr.Redirects)
+		assert.EqualError(t, err, fmt.Sprintf("redirect loop: %[1]s/loop-a -> %[1]s/loop-b -> %[1]s/loop-a", testServer.URL))
+	})
+
+	t.Run("records the redirect chain of checked links", func(t *testing.T) {
+		testServer := redirectServer(2)
+		defer testServer.Close()
+
+		page, err := NewChecker(NewFetcher()).Fetch(context.Background(), testServer.URL+"/1")
+		require.NoError(t, err)
+
+		assert.Equal(t, testServer.URL+"/2", page.URL)
+		assert.Len(t, page.Redirects, 1)
+	})
+}
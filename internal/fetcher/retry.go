@@ -0,0 +1,173 @@
+package fetcher
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryFetcher wraps another IFetcher and tries transient failures again with exponential backoff and jitter.
+// Permanent failures (see Classify) are returned straight away.
+type RetryFetcher struct {
+	fetcher     IFetcher
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+	sleep       func(ctx context.Context, d time.Duration) error
+}
+
+type retryOptions struct {
+	maxAttempts int
+	baseDelay   time.Duration
+	maxDelay    time.Duration
+	jitter      float64
+}
+
+type RetryOption func(o *retryOptions)
+
+// WithMaxAttempts limits the total no. of attempts per URL, including the first one.
+func WithMaxAttempts(n int) RetryOption {
+	return func(o *retryOptions) {
+		o.maxAttempts = n
+	}
+}
+
+// WithBaseDelay sets the delay before the first retry. It's doubled for every retry after that.
+func WithBaseDelay(d time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.baseDelay = d
+	}
+}
+
+// WithMaxDelay caps the delay between two attempts. A link whose Retry-After asks for longer than that isn't retried at all.
+func WithMaxDelay(d time.Duration) RetryOption {
+	return func(o *retryOptions) {
+		o.maxDelay = d
+	}
+}
+
+// WithJitter randomises each delay by up to the given fraction (0-1) so that workers don't retry in lockstep.
+func WithJitter(fraction float64) RetryOption {
+	return func(o *retryOptions) {
+		o.jitter = fraction
+	}
+}
+
+func NewRetryFetcher(fetcher IFetcher, opts ...RetryOption) *RetryFetcher {
+	o := &retryOptions{
+		maxAttempts: 3,
+		baseDelay:   500 * time.Millisecond,
+		maxDelay:    30 * time.Second,
+		jitter:      0.2,
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return &RetryFetcher{
+		fetcher:     fetcher,
+		maxAttempts: o.maxAttempts,
+		baseDelay:   o.baseDelay,
+		maxDelay:    o.maxDelay,
+		jitter:      math.Min(math.Max(o.jitter, 0), 1),
+		sleep:       sleep,
+	}
+}
+
+func (f *RetryFetcher) Fetch(ctx context.Context, targetUrl string) (*Page, error) {
+	var (
+		page *Page
+		err  error
+	)
+
+	for attempt := 1; ; attempt++ {
+		page, err = f.fetcher.Fetch(ctx, targetUrl)
+		if err == nil || !IsRetryable(err) || attempt >= f.maxAttempts || ctx.Err() != nil {
+			return page, err
+		}
+
+		delay, ok := f.delay(attempt, err)
+		if !ok {
+			log.Printf("giving up on %s - asked to retry after %v, past the max delay of %v - %v\n", targetUrl, delay, f.maxDelay, err)
+			return page, err
+		}
+		log.Printf("retrying %s in %v (attempt %d/%d) - %v\n", targetUrl, delay, attempt+1, f.maxAttempts, err)
+		if err := f.sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// sleep waits for d to pass, unless ctx is done first.
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// delay works out how long to wait before the next attempt. A Retry-After header on a 429/503 response takes precedence
+// over the exponential backoff. Retrying any sooner than it asks would be rude, so it reports false along with the delay
+// when that's more than maxDelay. The backoff, on the other hand, is capped by maxDelay, jitter included.
+func (f *RetryFetcher) delay(attempt int, err error) (time.Duration, bool) {
+	if d, ok := retryAfter(err); ok {
+		return d, f.maxDelay <= 0 || d <= f.maxDelay
+	}
+
+	d := time.Duration(float64(f.baseDelay) * math.Pow(2, float64(attempt-1)))
+	if f.jitter > 0 {
+		// Spread the delay evenly within ±jitter of itself.
+		d = time.Duration(float64(d) * (1 + f.jitter*(2*rand.Float64()-1)))
+	}
+
+	return f.capDelay(d), true
+}
+
+func (f *RetryFetcher) capDelay(d time.Duration) time.Duration {
+	if f.maxDelay > 0 && d > f.maxDelay {
+		return f.maxDelay
+	}
+	return d
+}
+
+// retryAfter parses the Retry-After header of 429 and 503 responses, which can either be in seconds or an HTTP date.
+func retryAfter(err error) (time.Duration, bool) {
+	var statusErr *HTTPStatusError
+	if !errors.As(err, &statusErr) || statusErr.Header == nil {
+		return 0, false
+	}
+
+	if statusErr.StatusCode != http.StatusTooManyRequests && statusErr.StatusCode != http.StatusServiceUnavailable {
+		return 0, false
+	}
+
+	v := statusErr.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+
+	if secs, err := strconv.Atoi(v); err == nil && secs >= 0 {
+		return time.Duration(secs) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(v); err == nil {
+		d := time.Until(t)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+
+	return 0, false
+}
@@ -0,0 +1,184 @@
+package fetcher
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first `failures` requests with the given status before serving a page with one link.
+func flakyServer(failures int64, status int, header http.Header) (*httptest.Server, *atomic.Int64) {
+	var hits atomic.Int64
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if hits.Add(1) <= failures {
+			for k, v := range header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(status)
+			return
+		}
+		fmt.Fprint(w, `<a href="/about/">About</a>`)
+	}))
+	return testServer, &hits
+}
+
+func newTestRetryFetcher(opts ...RetryOption) (*RetryFetcher, *[]time.Duration) {
+	var delays []time.Duration
+	f := NewRetryFetcher(NewFetcher(), opts...)
+	f.sleep = func(ctx context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	return f, &delays
+}
+
+func TestRetryFetcher_Fetch(t *testing.T) {
+	t.Run("when the page recovers before running out of attempts", func(t *testing.T) {
+		testServer, hits := flakyServer(2, http.StatusServiceUnavailable, nil)
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(3), WithBaseDelay(100*time.Millisecond), WithJitter(0))
+		page, err := f.Fetch(context.Background(), testServer.URL)
+
+		assert.Nil(t, err)
+		assert.Equal(t, []string{testServer.URL + "/about/"}, linkUrls(page.Links))
+		assert.Equal(t, int64(3), hits.Load())
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond}, *delays)
+	})
+
+	t.Run("when the page keeps failing", func(t *testing.T) {
+		testServer, hits := flakyServer(10, http.StatusBadGateway, nil)
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(4), WithBaseDelay(100*time.Millisecond), WithMaxDelay(250*time.Millisecond), WithJitter(0))
+		page, err := f.Fetch(context.Background(), testServer.URL)
+
+		var statusErr *HTTPStatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusBadGateway, statusErr.StatusCode)
+		assert.Nil(t, page)
+		assert.Equal(t, int64(4), hits.Load())
+		assert.Equal(t, []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 250 * time.Millisecond}, *delays)
+	})
+
+	t.Run("when the failure is permanent", func(t *testing.T) {
+		testServer, hits := flakyServer(10, http.StatusNotFound, nil)
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(5))
+		_, err := f.Fetch(context.Background(), testServer.URL)
+
+		assert.Equal(t, ErrorPermanent, Classify(err))
+		assert.Equal(t, int64(1), hits.Load())
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("when the page asks to retry after a number of seconds", func(t *testing.T) {
+		testServer, hits := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"7"}})
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(2), WithBaseDelay(time.Millisecond), WithMaxDelay(time.Minute))
+		_, err := f.Fetch(context.Background(), testServer.URL)
+
+		assert.Nil(t, err)
+		assert.Equal(t, int64(2), hits.Load())
+		assert.Equal(t, []time.Duration{7 * time.Second}, *delays)
+	})
+
+	t.Run("when the page asks to retry after a date", func(t *testing.T) {
+		retryAt := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+		testServer, _ := flakyServer(1, http.StatusServiceUnavailable, http.Header{"Retry-After": {retryAt}})
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(2), WithMaxDelay(2*time.Hour))
+		_, err := f.Fetch(context.Background(), testServer.URL)
+
+		assert.Nil(t, err)
+		require.Len(t, *delays, 1)
+		assert.InDelta(t, time.Hour, (*delays)[0], float64(5*time.Second))
+	})
+
+	t.Run("when the page asks to retry after more than the max delay", func(t *testing.T) {
+		testServer, hits := flakyServer(1, http.StatusTooManyRequests, http.Header{"Retry-After": {"120"}})
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(3), WithMaxDelay(10*time.Second))
+		page, err := f.Fetch(context.Background(), testServer.URL)
+
+		var statusErr *HTTPStatusError
+		require.ErrorAs(t, err, &statusErr)
+		assert.Equal(t, http.StatusTooManyRequests, statusErr.StatusCode)
+		assert.Nil(t, page)
+		assert.Equal(t, int64(1), hits.Load(), "shouldn't retry any sooner than asked")
+		assert.Empty(t, *delays)
+	})
+
+	t.Run("when retries are disabled", func(t *testing.T) {
+		testServer, hits := flakyServer(1, http.StatusServiceUnavailable, nil)
+		defer testServer.Close()
+
+		f, delays := newTestRetryFetcher(WithMaxAttempts(1))
+		_, err := f.Fetch(context.Background(), testServer.URL)
+
+		assert.Equal(t, ErrorRetryable, Classify(err))
+		assert.Equal(t, int64(1), hits.Load())
+		assert.Empty(t, *delays)
+	})
+}
+
+func TestRetryFetcher_sleep(t *testing.T) {
+	t.Run("stops backing off when the context is done", func(t *testing.T) {
+		testServer, hits := flakyServer(10, http.StatusServiceUnavailable, nil)
+		defer testServer.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		f := NewRetryFetcher(NewFetcher(), WithMaxAttempts(5), WithBaseDelay(time.Hour))
+
+		start := time.Now()
+		_, err := f.Fetch(ctx, testServer.URL)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, int64(1), hits.Load())
+	})
+}
+
+func TestRetryFetcher_delay(t *testing.T) {
+	t.Run("adds jitter within bounds", func(t *testing.T) {
+		f := NewRetryFetcher(NewMockFetcher(), WithBaseDelay(time.Second), WithJitter(0.5))
+		err := &HTTPStatusError{StatusCode: http.StatusBadGateway}
+
+		for i := 0; i < 100; i++ {
+			d, ok := f.delay(2, err)
+			assert.True(t, ok)
+			assert.GreaterOrEqual(t, d, time.Second)
+			assert.LessOrEqual(t, d, 3*time.Second)
+		}
+	})
+
+	t.Run("caps the delay after adding jitter", func(t *testing.T) {
+		f := NewRetryFetcher(NewMockFetcher(), WithBaseDelay(time.Second), WithMaxDelay(time.Second), WithJitter(0.5))
+		err := &HTTPStatusError{StatusCode: http.StatusBadGateway}
+
+		for i := 0; i < 100; i++ {
+			d, _ := f.delay(3, err)
+			assert.Equal(t, time.Second, d)
+		}
+	})
+
+	t.Run("ignores Retry-After on other statuses", func(t *testing.T) {
+		f := NewRetryFetcher(NewMockFetcher(), WithBaseDelay(time.Second), WithJitter(0))
+		err := &HTTPStatusError{StatusCode: http.StatusBadGateway, Header: http.Header{"Retry-After": {"60"}}}
+
+		d, ok := f.delay(1, err)
+		assert.True(t, ok)
+		assert.Equal(t, time.Second, d)
+	})
+}
@@ -0,0 +1,170 @@
+package graph
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatDOT     = "dot"
+	FormatGraphML = "graphml"
+	FormatCSV     = "csv"
+)
+
+// Edge is a link from one page to another.
+type Edge struct {
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	Text     string `json:"text,omitempty"`     // The link's anchor text.
+	Position int    `json:"position,omitempty"` // Where the link appears on the source page, counting from 1.
+	Fragment string `json:"fragment,omitempty"` // The part of the link after the #, if any. It's not part of Target.
+	Kind     string `json:"kind,omitempty"`     // What the link is used for on the source page, e.g. navigation or image.
+	NoFollow bool   `json:"noFollow,omitempty"` // Whether the link asked not to be followed, with rel="nofollow" or the source page's robots directives.
+}
+
+type IGraph interface {
+	AddEdge(e Edge)
+}
+
+// Graph is a directed graph of the links between crawled pages. It's safe for concurrent use.
+type Graph struct {
+	edges []Edge
+	lock  sync.Mutex
+}
+
+func NewGraph() *Graph {
+	return &Graph{}
+}
+
+func (g *Graph) AddEdge(e Edge) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.edges = append(g.edges, e)
+}
+
+// Edges returns every edge, sorted by source page and then by position on the page.
+func (g *Graph) Edges() []Edge {
+	g.lock.Lock()
+	edges := append([]Edge(nil), g.edges...)
+	g.lock.Unlock()
+
+	sort.SliceStable(edges, func(i, j int) bool {
+		if edges[i].Source != edges[j].Source {
+			return edges[i].Source < edges[j].Source
+		}
+		return edges[i].Position < edges[j].Position
+	})
+
+	return edges
+}
+
+// Nodes returns every page that's either the source or the target of an edge, sorted.
+func (g *Graph) Nodes() []string {
+	seen := make(map[string]bool)
+	for _, e := range g.Edges() {
+		seen[e.Source] = true
+		seen[e.Target] = true
+	}
+
+	nodes := make([]string, 0, len(seen))
+	for n := range seen {
+		nodes = append(nodes, n)
+	}
+	sort.Strings(nodes)
+
+	return nodes
+}
+
+// Write exports the graph in the given format: dot, graphml or csv.
+func (g *Graph) Write(w io.Writer, format string) error {
+	switch format {
+	case FormatDOT:
+		return g.WriteDOT(w)
+	case FormatGraphML:
+		return g.WriteGraphML(w)
+	case FormatCSV:
+		return g.WriteCSV(w)
+	default:
+		return fmt.Errorf("unknown graph format %q", format)
+	}
+}
+
+// WriteDOT exports the graph for Graphviz, labelling each edge with its anchor text. The rest of the edge's fields are
+// kept as attributes, which Graphviz ignores.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("digraph crawl {\n")
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(&b, "\t%s;\n", dotQuote(n))
+	}
+	for _, e := range g.Edges() {
+		fmt.Fprintf(&b, "\t%s -> %s [label=%s, position=%d, kind=%s, fragment=%s, nofollow=%t];\n",
+			dotQuote(e.Source), dotQuote(e.Target), dotQuote(e.Text), e.Position, dotQuote(e.Kind), dotQuote(e.Fragment), e.NoFollow)
+	}
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// WriteGraphML exports the graph as GraphML, which Gephi, yEd and Cytoscape can open. Nodes are keyed by their URL.
+func (g *Graph) WriteGraphML(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">` + "\n")
+	b.WriteString(`  <key id="text" for="edge" attr.name="text" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="position" for="edge" attr.name="position" attr.type="int"/>` + "\n")
+	b.WriteString(`  <key id="kind" for="edge" attr.name="kind" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="fragment" for="edge" attr.name="fragment" attr.type="string"/>` + "\n")
+	b.WriteString(`  <key id="nofollow" for="edge" attr.name="nofollow" attr.type="boolean"/>` + "\n")
+	b.WriteString(`  <graph id="crawl" edgedefault="directed">` + "\n")
+	for _, n := range g.Nodes() {
+		fmt.Fprintf(&b, "    <node id=\"%s\"/>\n", xmlEscape(n))
+	}
+	for i, e := range g.Edges() {
+		fmt.Fprintf(&b, "    <edge id=\"e%d\" source=\"%s\" target=\"%s\">\n", i, xmlEscape(e.Source), xmlEscape(e.Target))
+		fmt.Fprintf(&b, "      <data key=\"text\">%s</data>\n", xmlEscape(e.Text))
+		fmt.Fprintf(&b, "      <data key=\"position\">%d</data>\n", e.Position)
+		fmt.Fprintf(&b, "      <data key=\"kind\">%s</data>\n", xmlEscape(e.Kind))
+		fmt.Fprintf(&b, "      <data key=\"fragment\">%s</data>\n", xmlEscape(e.Fragment))
+		fmt.Fprintf(&b, "      <data key=\"nofollow\">%t</data>\n", e.NoFollow)
+		b.WriteString("    </edge>\n")
+	}
+	b.WriteString("  </graph>\n</graphml>\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// WriteCSV exports the graph as an edge list with a header row.
+func (g *Graph) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"source", "target", "text", "position", "kind", "fragment", "noFollow"}); err != nil {
+		return err
+	}
+	for _, e := range g.Edges() {
+		if err := cw.Write([]string{e.Source, e.Target, e.Text, strconv.Itoa(e.Position), e.Kind, e.Fragment, strconv.FormatBool(e.NoFollow)}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
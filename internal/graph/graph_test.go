@@ -0,0 +1,96 @@
+package graph
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func testGraph() *Graph {
+	g := NewGraph()
+	g.AddEdge(Edge{Source: "https://monzo.com/help/", Target: "https://monzo.com/", Text: "Home", Position: 1, Kind: "navigation"})
+	g.AddEdge(Edge{Source: "https://monzo.com/", Target: "https://monzo.com/help/", Text: `Get "help"`, Position: 2, Fragment: "faq", Kind: "navigation", NoFollow: true})
+	g.AddEdge(Edge{Source: "https://monzo.com/", Target: "https://monzo.com/search?q=a&b", Text: "Search", Position: 1})
+	return g
+}
+
+func TestGraph(t *testing.T) {
+	t.Run("sorts edges by source and position", func(t *testing.T) {
+		g := testGraph()
+
+		assert.Equal(t, []Edge{
+			{Source: "https://monzo.com/", Target: "https://monzo.com/search?q=a&b", Text: "Search", Position: 1},
+			{Source: "https://monzo.com/", Target: "https://monzo.com/help/", Text: `Get "help"`, Position: 2, Fragment: "faq", Kind: "navigation", NoFollow: true},
+			{Source: "https://monzo.com/help/", Target: "https://monzo.com/", Text: "Home", Position: 1, Kind: "navigation"},
+		}, g.Edges())
+		assert.Equal(t, []string{"https://monzo.com/", "https://monzo.com/help/", "https://monzo.com/search?q=a&b"}, g.Nodes())
+	})
+
+	t.Run("writes DOT", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, testGraph().Write(&b, FormatDOT))
+
+		assert.Equal(t, `digraph crawl {
+	"https://monzo.com/";
+	"https://monzo.com/help/";
+	"https://monzo.com/search?q=a&b";
+	"https://monzo.com/" -> "https://monzo.com/search?q=a&b" [label="Search", position=1, kind="", fragment="", nofollow=false];
+	"https://monzo.com/" -> "https://monzo.com/help/" [label="Get \"help\"", position=2, kind="navigation", fragment="faq", nofollow=true];
+	"https://monzo.com/help/" -> "https://monzo.com/" [label="Home", position=1, kind="navigation", fragment="", nofollow=false];
+}
+`, b.String())
+	})
+
+	t.Run("writes GraphML", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, testGraph().Write(&b, FormatGraphML))
+
+		var doc struct {
+			Graph struct {
+				Nodes []struct {
+					ID string `xml:"id,attr"`
+				} `xml:"node"`
+				Edges []struct {
+					Source string `xml:"source,attr"`
+					Target string `xml:"target,attr"`
+					Data   []struct {
+						Key   string `xml:"key,attr"`
+						Value string `xml:",chardata"`
+					} `xml:"data"`
+				} `xml:"edge"`
+			} `xml:"graph"`
+		}
+		require.NoError(t, xml.Unmarshal(b.Bytes(), &doc))
+
+		require.Len(t, doc.Graph.Nodes, 3)
+		assert.Equal(t, "https://monzo.com/search?q=a&b", doc.Graph.Nodes[2].ID)
+
+		require.Len(t, doc.Graph.Edges, 3)
+		edge := doc.Graph.Edges[1]
+		assert.Equal(t, "https://monzo.com/", edge.Source)
+		assert.Equal(t, "https://monzo.com/help/", edge.Target)
+		require.Len(t, edge.Data, 5)
+		assert.Equal(t, `Get "help"`, edge.Data[0].Value)
+		assert.Equal(t, "2", edge.Data[1].Value)
+		assert.Equal(t, "navigation", edge.Data[2].Value)
+		assert.Equal(t, "faq", edge.Data[3].Value)
+		assert.Equal(t, "true", edge.Data[4].Value)
+	})
+
+	t.Run("writes an edge list", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, testGraph().Write(&b, FormatCSV))
+
+		assert.Equal(t, `source,target,text,position,kind,fragment,noFollow
+https://monzo.com/,https://monzo.com/search?q=a&b,Search,1,,,false
+https://monzo.com/,https://monzo.com/help/,"Get ""help""",2,navigation,faq,true
+https://monzo.com/help/,https://monzo.com/,Home,1,navigation,,false
+`, b.String())
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		assert.ErrorContains(t, testGraph().Write(&bytes.Buffer{}, "svg"), `unknown graph format "svg"`)
+	})
+}
@@ -0,0 +1,131 @@
+package linkcheck
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"webcrawler-go/internal/crawler"
+	"webcrawler-go/internal/fetcher"
+	"webcrawler-go/internal/graph"
+)
+
+const (
+	KindPage     = "page"     // The linked page didn't work.
+	KindFragment = "fragment" // The linked page worked, but nothing on it matches the link's #fragment.
+)
+
+// BrokenLink is a link that didn't work, along with every page it was found on.
+type BrokenLink struct {
+	URL        string // Including the #fragment for KindFragment.
+	Kind       string
+	StatusCode int    // The HTTP status code, or 0 if there was no response.
+	Error      string // Why it didn't work.
+	Sources    []Source
+}
+
+// Source is a page that a broken link was found on.
+type Source struct {
+	URL  string
+	Text string // The link's anchor text.
+}
+
+// Find picks out the links that failed from a crawl's results, and looks up the pages they were found on in its graph.
+// Links that failed without ever being linked to, e.g. the starting URL, have no sources.
+// Links with a #fragment to a crawled HTML page are also broken when the page has no id or <a name> that matches it. Broken
+// pages come first, followed by dangling fragments.
+func Find(results []*crawler.PageResult, edges []graph.Edge) []BrokenLink {
+	sources := make(map[string][]Source)
+	fragmentSources := make(map[string][]Source)
+	for _, e := range edges {
+		sources[e.Target] = append(sources[e.Target], Source{URL: e.Source, Text: e.Text})
+		if e.Fragment != "" {
+			key := e.Target + "#" + e.Fragment
+			fragmentSources[key] = append(fragmentSources[key], Source{URL: e.Source, Text: e.Text})
+		}
+	}
+
+	broken := make([]BrokenLink, 0)
+	pages := make(map[string]*crawler.PageResult, len(results))
+	for _, r := range results {
+		pages[r.URL] = r
+		if r.Status == crawler.PageFailed {
+			broken = append(broken, BrokenLink{URL: r.URL, Kind: KindPage, StatusCode: r.StatusCode, Error: r.Error, Sources: sorted(sources[r.URL])})
+		}
+	}
+
+	for _, e := range edges {
+		key := e.Target + "#" + e.Fragment
+		s, ok := fragmentSources[key]
+		if !ok || !dangling(pages[e.Target], e.Fragment) {
+			continue
+		}
+		// Every link to the same fragment is reported together, the first time it comes up.
+		delete(fragmentSources, key)
+
+		u := e.Target + "#" + (&url.URL{Fragment: e.Fragment}).EscapedFragment()
+		broken = append(broken, BrokenLink{URL: u, Kind: KindFragment, Error: fmt.Sprintf("no element with id or name %q", e.Fragment), Sources: sorted(s)})
+	}
+
+	sort.SliceStable(broken, func(i, j int) bool {
+		if broken[i].Kind != broken[j].Kind {
+			return broken[i].Kind == KindPage
+		}
+		return broken[i].URL < broken[j].URL
+	})
+
+	return broken
+}
+
+// dangling reports whether a fragment doesn't match anything on the crawled HTML page. Fragments that browsers or
+// scripts handle on their own, e.g. #top or #!/route, are never dangling. Pages that weren't crawled can't be checked.
+func dangling(page *crawler.PageResult, fragment string) bool {
+	if page == nil || page.Status != crawler.PageSucceeded || !fetcher.IsHtml(page.ContentType) {
+		return false
+	}
+
+	if strings.EqualFold(fragment, "top") || strings.HasPrefix(fragment, ":~:") || strings.HasPrefix(fragment, "!") || strings.HasPrefix(fragment, "/") {
+		return false
+	}
+
+	for _, a := range page.Anchors {
+		if a == fragment {
+			return false
+		}
+	}
+	return true
+}
+
+func sorted(sources []Source) []Source {
+	sort.SliceStable(sources, func(i, j int) bool { return sources[i].URL < sources[j].URL })
+	return sources
+}
+
+// Write prints the broken links for people to read, e.g. in CI logs, with the pages that link to each of them underneath.
+func Write(w io.Writer, broken []BrokenLink) error {
+	for _, b := range broken {
+		status := b.Error
+		if b.StatusCode != 0 {
+			status = fmt.Sprintf("%d %s", b.StatusCode, http.StatusText(b.StatusCode))
+		}
+
+		if _, err := fmt.Fprintf(w, "%s (%s)\n", b.URL, status); err != nil {
+			return err
+		}
+
+		if len(b.Sources) == 0 {
+			if _, err := fmt.Fprintln(w, "\tnot linked from any crawled page"); err != nil {
+				return err
+			}
+		}
+		for _, s := range b.Sources {
+			if _, err := fmt.Fprintf(w, "\tlinked from %s as %q\n", s.URL, s.Text); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package linkcheck
+
+import (
+	"bytes"
+	"testing"
+	"webcrawler-go/internal/crawler"
+	"webcrawler-go/internal/graph"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFind(t *testing.T) {
+	results := []*crawler.PageResult{
+		{URL: "https://monzo.com/", Status: crawler.PageSucceeded, StatusCode: 200},
+		{URL: "https://monzo.com/help/", Status: crawler.PageFailed, StatusCode: 404, Error: "permanent error: unexpected status 404 Not Found"},
+		{URL: "https://twitter.com/monzo", Status: crawler.PageChecked, StatusCode: 200},
+		{URL: "https://gone.monzo.com/", Status: crawler.PageFailed, Error: "permanent error: no such host"},
+		{URL: "https://monzo.com/switch/", Status: crawler.PageBlocked},
+	}
+	edges := []graph.Edge{
+		{Source: "https://monzo.com/", Target: "https://twitter.com/monzo", Text: "Twitter"},
+		{Source: "https://monzo.com/current-account/", Target: "https://monzo.com/help/", Text: "Get help"},
+		{Source: "https://monzo.com/", Target: "https://monzo.com/help/", Text: "Help"},
+		{Source: "https://monzo.com/", Target: "https://gone.monzo.com/", Text: ""},
+	}
+
+	broken := Find(results, edges)
+
+	t.Run("finds every failed link with the pages that link to it", func(t *testing.T) {
+		assert.Equal(t, []BrokenLink{
+			{
+				URL:     "https://gone.monzo.com/",
+				Kind:    KindPage,
+				Error:   "permanent error: no such host",
+				Sources: []Source{{URL: "https://monzo.com/"}},
+			},
+			{
+				URL:        "https://monzo.com/help/",
+				Kind:       KindPage,
+				StatusCode: 404,
+				Error:      "permanent error: unexpected status 404 Not Found",
+				Sources: []Source{
+					{URL: "https://monzo.com/", Text: "Help"},
+					{URL: "https://monzo.com/current-account/", Text: "Get help"},
+				},
+			},
+		}, broken)
+	})
+
+	t.Run("writes the broken links for people to read", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, Write(&buf, append(broken, BrokenLink{URL: "https://monzo.com/", StatusCode: 500})))
+
+		assert.Equal(t, "https://gone.monzo.com/ (permanent error: no such host)\n"+
+			"\tlinked from https://monzo.com/ as \"\"\n"+
+			"https://monzo.com/help/ (404 Not Found)\n"+
+			"\tlinked from https://monzo.com/ as \"Help\"\n"+
+			"\tlinked from https://monzo.com/current-account/ as \"Get help\"\n"+
+			"https://monzo.com/ (500 Internal Server Error)\n"+
+			"\tnot linked from any crawled page\n", buf.String())
+	})
+}
+
+func TestFind_fragments(t *testing.T) {
+	results := []*crawler.PageResult{
+		{URL: "https://monzo.com/", Status: crawler.PageSucceeded, ContentType: "text/html", Anchors: []string{"main"}},
+		{URL: "https://monzo.com/docs/", Status: crawler.PageSucceeded, ContentType: "text/html; charset=utf-8", Anchors: []string{"install", "usage"}},
+		{URL: "https://monzo.com/terms.pdf", Status: crawler.PageSucceeded, ContentType: "application/pdf"},
+		{URL: "https://monzo.com/fragment.json", Status: crawler.PageSucceeded, ContentType: "application/x-html-fragment+json"},
+		{URL: "https://twitter.com/monzo", Status: crawler.PageChecked, ContentType: "text/html"},
+	}
+	edges := []graph.Edge{
+		{Source: "https://monzo.com/", Target: "https://monzo.com/docs/", Text: "Install", Fragment: "install"},
+		{Source: "https://monzo.com/", Target: "https://monzo.com/docs/", Text: "Configure", Fragment: "config uration"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/docs/", Text: "Configuration", Fragment: "config uration"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/", Text: "Skip to content", Fragment: "main"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/docs/", Text: "Back to top", Fragment: "top"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/docs/", Text: "App", Fragment: "!/app"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/terms.pdf", Text: "Terms", Fragment: "page=2"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/fragment.json", Text: "Data", Fragment: "rates"},
+		{Source: "https://monzo.com/docs/", Target: "https://twitter.com/monzo", Text: "Twitter", Fragment: "tweets"},
+		{Source: "https://monzo.com/docs/", Target: "https://monzo.com/help/", Text: "Help", Fragment: "faq"},
+	}
+
+	assert.Equal(t, []BrokenLink{
+		{
+			URL:   "https://monzo.com/docs/#config%20uration",
+			Kind:  KindFragment,
+			Error: `no element with id or name "config uration"`,
+			Sources: []Source{
+				{URL: "https://monzo.com/", Text: "Configure"},
+				{URL: "https://monzo.com/docs/", Text: "Configuration"},
+			},
+		},
+	}, Find(results, edges))
+}
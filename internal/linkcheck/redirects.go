@@ -0,0 +1,73 @@
+package linkcheck
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"webcrawler-go/internal/crawler"
+	"webcrawler-go/internal/fetcher"
+)
+
+// RedirectChain is a page whose redirects are worth a look, either because there are a lot of them or because they lead
+// outside the crawl's scope.
+type RedirectChain struct {
+	URL        string
+	FinalURL   string
+	Redirects  []fetcher.Redirect
+	OutOfScope bool
+}
+
+// FindRedirects picks out the pages from a crawl's results that took at least longChain redirects to get to, or that
+// redirected outside the crawl's scope, sorted by URL. Every page's final URL is checked against scope, whatever its status,
+// from the page it was found on, so checked links that redirect elsewhere are caught too.
+func FindRedirects(results []*crawler.PageResult, scope *crawler.Scope, longChain int) []RedirectChain {
+	chains := make([]RedirectChain, 0)
+	for _, r := range results {
+		if r.FinalURL == "" {
+			continue
+		}
+
+		from := r.Parent
+		if from == "" {
+			from = r.URL
+		}
+		_, inScope := scope.Check(from, r.FinalURL)
+		outOfScope := !inScope
+		if len(r.Redirects) < longChain && !outOfScope {
+			continue
+		}
+
+		chains = append(chains, RedirectChain{URL: r.URL, FinalURL: r.FinalURL, Redirects: r.Redirects, OutOfScope: outOfScope})
+	}
+
+	sort.Slice(chains, func(i, j int) bool { return chains[i].URL < chains[j].URL })
+
+	return chains
+}
+
+// WriteRedirectsCSV writes the chains with url,finalUrl,hops,outOfScope,chain columns, where chain lists every hop with its
+// status code, e.g. "301 http://monzo.com/a -> 308 https://monzo.com/a -> https://monzo.com/a/".
+func WriteRedirectsCSV(w io.Writer, chains []RedirectChain) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"url", "finalUrl", "hops", "outOfScope", "chain"}); err != nil {
+		return err
+	}
+
+	for _, c := range chains {
+		hops := make([]string, 0, len(c.Redirects)+1)
+		for _, r := range c.Redirects {
+			hops = append(hops, fmt.Sprintf("%d %s", r.StatusCode, r.URL))
+		}
+		hops = append(hops, c.FinalURL)
+
+		if err := cw.Write([]string{c.URL, c.FinalURL, strconv.Itoa(len(c.Redirects)), strconv.FormatBool(c.OutOfScope), strings.Join(hops, " -> ")}); err != nil {
+			return err
+		}
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,75 @@
+package linkcheck
+
+import (
+	"bytes"
+	"testing"
+	"webcrawler-go/internal/crawler"
+	"webcrawler-go/internal/fetcher"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindRedirects(t *testing.T) {
+	results := []*crawler.PageResult{
+		{URL: "https://monzo.com/", Status: crawler.PageSucceeded},
+		{
+			URL: "http://monzo.com/a", Status: crawler.PageSucceeded, FinalURL: "https://monzo.com/a/",
+			Redirects: []fetcher.Redirect{{URL: "http://monzo.com/a", StatusCode: 301}, {URL: "https://monzo.com/a", StatusCode: 308}},
+		},
+		{
+			URL: "https://monzo.com/b", Status: crawler.PageSucceeded, FinalURL: "https://monzo.com/b/",
+			Redirects: []fetcher.Redirect{{URL: "https://monzo.com/b", StatusCode: 301}},
+		},
+		{
+			URL: "https://monzo.com/blog", Status: crawler.PageOutOfScope, FinalURL: "https://medium.com/monzo",
+			Redirects: []fetcher.Redirect{{URL: "https://monzo.com/blog", StatusCode: 302}},
+		},
+		{
+			URL: "https://monzo.com/go/twitter", Status: crawler.PageChecked, Parent: "https://monzo.com/", FinalURL: "https://twitter.com/monzo",
+			Redirects: []fetcher.Redirect{{URL: "https://monzo.com/go/twitter", StatusCode: 302}},
+		},
+		{
+			URL: "https://monzo.com/c", Status: crawler.PageChecked, Parent: "https://monzo.com/", FinalURL: "https://monzo.com/c/",
+			Redirects: []fetcher.Redirect{{URL: "https://monzo.com/c", StatusCode: 301}},
+		},
+		{
+			URL: "https://monzo.com/loop", Status: crawler.PageFailed,
+			Redirects: []fetcher.Redirect{{URL: "https://monzo.com/loop", StatusCode: 302}},
+		},
+	}
+
+	scope, err := crawler.NewScope()
+	require.NoError(t, err)
+	chains := FindRedirects(results, scope, 2)
+
+	t.Run("finds long chains and redirects out of scope", func(t *testing.T) {
+		assert.Equal(t, []RedirectChain{
+			{URL: "http://monzo.com/a", FinalURL: "https://monzo.com/a/", Redirects: results[1].Redirects},
+			{URL: "https://monzo.com/blog", FinalURL: "https://medium.com/monzo", Redirects: results[3].Redirects, OutOfScope: true},
+			{URL: "https://monzo.com/go/twitter", FinalURL: "https://twitter.com/monzo", Redirects: results[4].Redirects, OutOfScope: true},
+		}, chains)
+	})
+
+	t.Run("checks where checked links redirect to against the scope", func(t *testing.T) {
+		scope, err := crawler.NewScope(crawler.WithPathPrefixes("/c"))
+		require.NoError(t, err)
+
+		chains := FindRedirects(results[5:6], scope, 2)
+		assert.Empty(t, chains, "links that redirect within the scope shouldn't be reported")
+
+		chains = FindRedirects(results[4:5], scope, 2)
+		require.Len(t, chains, 1)
+		assert.True(t, chains[0].OutOfScope)
+	})
+
+	t.Run("writes the chains as CSV", func(t *testing.T) {
+		var buf bytes.Buffer
+		require.NoError(t, WriteRedirectsCSV(&buf, chains))
+
+		assert.Equal(t, "url,finalUrl,hops,outOfScope,chain\n"+
+			"http://monzo.com/a,https://monzo.com/a/,2,false,301 http://monzo.com/a -> 308 https://monzo.com/a -> https://monzo.com/a/\n"+
+			"https://monzo.com/blog,https://medium.com/monzo,1,true,302 https://monzo.com/blog -> https://medium.com/monzo\n"+
+			"https://monzo.com/go/twitter,https://twitter.com/monzo,1,true,302 https://monzo.com/go/twitter -> https://twitter.com/monzo\n", buf.String())
+	})
+}
@@ -0,0 +1,169 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"webcrawler-go/internal/crawler"
+)
+
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+)
+
+// Record is the stable schema of a page in the output. Every field is always present, so that downstream tools can rely on it.
+type Record struct {
+	URL         string  `json:"url"`
+	Status      string  `json:"status"`
+	StatusCode  int     `json:"statusCode"`
+	Depth       int     `json:"depth"`
+	Parent      string  `json:"parent"`
+	LatencyMs   float64 `json:"latencyMs"`
+	ContentType string  `json:"contentType"`
+	Size        int64   `json:"size"`
+	Error       string  `json:"error"`
+	FinalURL    string  `json:"finalUrl"`
+	Redirects   int     `json:"redirects"`
+	Kind        string  `json:"kind"`
+	NoIndex     bool    `json:"noIndex"`
+	NoFollow    bool    `json:"noFollow"`
+}
+
+// csvHeader lists the CSV columns, in the same order as Record's fields.
+var csvHeader = []string{"url", "status", "statusCode", "depth", "parent", "latencyMs", "contentType", "size", "error", "finalUrl", "redirects", "kind", "noIndex", "noFollow"}
+
+func NewRecord(r *crawler.PageResult) Record {
+	return Record{
+		URL:         r.URL,
+		Status:      string(r.Status),
+		StatusCode:  r.StatusCode,
+		Depth:       r.Depth,
+		Parent:      r.Parent,
+		LatencyMs:   float64(r.Latency.Microseconds()) / 1000,
+		ContentType: r.ContentType,
+		Size:        r.Size,
+		Error:       r.Error,
+		FinalURL:    r.FinalURL,
+		Redirects:   len(r.Redirects),
+		Kind:        string(r.Kind),
+		NoIndex:     r.NoIndex,
+		NoFollow:    r.NoFollow,
+	}
+}
+
+// Summary describes the crawl as a whole. It's only part of the JSON output.
+type Summary struct {
+	StartedAt    time.Time      `json:"startedAt"`
+	DurationMs   int64          `json:"durationMs"`
+	Pages        int            `json:"pages"`
+	Statuses     map[string]int `json:"statuses"`     // The no. of pages per status.
+	StoppedEarly string         `json:"stoppedEarly"` // Why the crawl stopped before it was done, if it did.
+}
+
+func NewSummary(startedAt time.Time, duration time.Duration, results []*crawler.PageResult, stopErr error) Summary {
+	counts := make(map[crawler.PageStatus]int)
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	return NewSummaryFromCounts(startedAt, duration, counts, stopErr)
+}
+
+// NewSummaryFromCounts summarises a crawl whose results weren't kept, from the no. of pages per status.
+func NewSummaryFromCounts(startedAt time.Time, duration time.Duration, counts map[crawler.PageStatus]int, stopErr error) Summary {
+	s := Summary{
+		StartedAt:  startedAt,
+		DurationMs: duration.Milliseconds(),
+		Statuses:   make(map[string]int, len(counts)),
+	}
+	for status, n := range counts {
+		s.Pages += n
+		s.Statuses[string(status)] += n
+	}
+	if stopErr != nil {
+		s.StoppedEarly = stopErr.Error()
+	}
+	return s
+}
+
+// Write writes one record per result in the given format: ndjson, csv or json.
+func Write(w io.Writer, format string, summary Summary, results []*crawler.PageResult) error {
+	switch format {
+	case FormatNDJSON:
+		return WriteNDJSON(w, results)
+	case FormatCSV:
+		return WriteCSV(w, results)
+	case FormatJSON:
+		return WriteJSON(w, summary, results)
+	default:
+		return fmt.Errorf("unknown output format %q", format)
+	}
+}
+
+// WriteNDJSON writes one JSON object per line.
+func WriteNDJSON(w io.Writer, results []*crawler.PageResult) error {
+	enc := json.NewEncoder(w)
+	for _, r := range results {
+		if err := enc.Encode(NewRecord(r)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteCSV writes a header row followed by one row per result.
+func WriteCSV(w io.Writer, results []*crawler.PageResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, r := range results {
+		if err := cw.Write(NewRecord(r).csvRow()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
+
+func (rec Record) csvRow() []string {
+	return []string{
+		rec.URL,
+		rec.Status,
+		strconv.Itoa(rec.StatusCode),
+		strconv.Itoa(rec.Depth),
+		rec.Parent,
+		strconv.FormatFloat(rec.LatencyMs, 'f', -1, 64),
+		rec.ContentType,
+		strconv.FormatInt(rec.Size, 10),
+		rec.Error,
+		rec.FinalURL,
+		strconv.Itoa(rec.Redirects),
+		rec.Kind,
+		strconv.FormatBool(rec.NoIndex),
+		strconv.FormatBool(rec.NoFollow),
+	}
+}
+
+// WriteJSON writes a single JSON document holding the summary and every record.
+func WriteJSON(w io.Writer, summary Summary, results []*crawler.PageResult) error {
+	doc := struct {
+		Summary Summary  `json:"summary"`
+		Pages   []Record `json:"pages"`
+	}{
+		Summary: summary,
+		Pages:   make([]Record, len(results)),
+	}
+	for i, r := range results {
+		doc.Pages[i] = NewRecord(r)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,85 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"webcrawler-go/internal/crawler"
+	"webcrawler-go/internal/fetcher"
+)
+
+func testResults() []*crawler.PageResult {
+	return []*crawler.PageResult{
+		{URL: "https://monzo.com/", Status: crawler.PageSucceeded, StatusCode: 200, Depth: 1, Latency: 1500 * time.Microsecond, ContentType: "text/html", Size: 512},
+		{URL: "https://monzo.com/help/", Status: crawler.PageFailed, StatusCode: 404, Depth: 2, Parent: "https://monzo.com/", Latency: 2 * time.Millisecond, Kind: fetcher.KindNavigation, Error: "permanent error: unexpected status 404, \"Not Found\""},
+		{URL: "https://twitter.com/monzo", Status: crawler.PageOutOfScope, Depth: 2, Parent: "https://monzo.com/", Kind: fetcher.KindNavigation},
+	}
+}
+
+func TestWrite(t *testing.T) {
+	summary := NewSummary(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), 3*time.Second, testResults(), errors.New("context deadline exceeded"))
+
+	t.Run("writes NDJSON", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, Write(&b, FormatNDJSON, summary, testResults()))
+
+		assert.Equal(t, `{"url":"https://monzo.com/","status":"succeeded","statusCode":200,"depth":1,"parent":"","latencyMs":1.5,"contentType":"text/html","size":512,"error":"","finalUrl":"","redirects":0,"kind":"","noIndex":false,"noFollow":false}
+{"url":"https://monzo.com/help/","status":"failed","statusCode":404,"depth":2,"parent":"https://monzo.com/","latencyMs":2,"contentType":"","size":0,"error":"permanent error: unexpected status 404, \"Not Found\"","finalUrl":"","redirects":0,"kind":"navigation","noIndex":false,"noFollow":false}
+{"url":"https://twitter.com/monzo","status":"out-of-scope","statusCode":0,"depth":2,"parent":"https://monzo.com/","latencyMs":0,"contentType":"","size":0,"error":"","finalUrl":"","redirects":0,"kind":"navigation","noIndex":false,"noFollow":false}
+`, b.String())
+	})
+
+	t.Run("writes CSV", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, Write(&b, FormatCSV, summary, testResults()))
+
+		assert.Equal(t, `url,status,statusCode,depth,parent,latencyMs,contentType,size,error,finalUrl,redirects,kind,noIndex,noFollow
+https://monzo.com/,succeeded,200,1,,1.5,text/html,512,,,0,,false,false
+https://monzo.com/help/,failed,404,2,https://monzo.com/,2,,0,"permanent error: unexpected status 404, ""Not Found""",,0,navigation,false,false
+https://twitter.com/monzo,out-of-scope,0,2,https://monzo.com/,0,,0,,,0,navigation,false,false
+`, b.String())
+	})
+
+	t.Run("writes JSON with a summary", func(t *testing.T) {
+		var b bytes.Buffer
+		require.NoError(t, Write(&b, FormatJSON, summary, testResults()))
+
+		var doc struct {
+			Summary Summary  `json:"summary"`
+			Pages   []Record `json:"pages"`
+		}
+		require.NoError(t, json.Unmarshal(b.Bytes(), &doc))
+
+		assert.Equal(t, 3, doc.Summary.Pages)
+		assert.Equal(t, int64(3000), doc.Summary.DurationMs)
+		assert.Equal(t, map[string]int{"succeeded": 1, "failed": 1, "out-of-scope": 1}, doc.Summary.Statuses)
+		assert.Equal(t, "context deadline exceeded", doc.Summary.StoppedEarly)
+		require.Len(t, doc.Pages, 3)
+		assert.Equal(t, NewRecord(testResults()[1]), doc.Pages[1])
+	})
+
+	t.Run("records where redirects led to", func(t *testing.T) {
+		rec := NewRecord(&crawler.PageResult{
+			URL:       "http://monzo.com/a",
+			Status:    crawler.PageSucceeded,
+			FinalURL:  "https://monzo.com/a/",
+			Redirects: []fetcher.Redirect{{URL: "http://monzo.com/a", StatusCode: 301}, {URL: "https://monzo.com/a", StatusCode: 308}},
+		})
+		assert.Equal(t, "https://monzo.com/a/", rec.FinalURL)
+		assert.Equal(t, 2, rec.Redirects)
+	})
+
+	t.Run("records robots directives", func(t *testing.T) {
+		rec := NewRecord(&crawler.PageResult{URL: "https://monzo.com/private/", Status: crawler.PageSucceeded, NoIndex: true, NoFollow: true})
+		assert.True(t, rec.NoIndex)
+		assert.True(t, rec.NoFollow)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		assert.ErrorContains(t, Write(&bytes.Buffer{}, "xml", summary, nil), `unknown output format "xml"`)
+	})
+}
@@ -0,0 +1,110 @@
+package output
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"webcrawler-go/internal/crawler"
+)
+
+// StreamSink writes every result as soon as it's recorded, rather than once the crawl is done, and only counts them by status
+// for the summary. It's meant for crawls too large to keep every result in memory. Close must be called once the crawl is
+// done to flush the output, and to write the summary of JSON output after its pages.
+type StreamSink struct {
+	*crawler.CountingSink
+	format string
+	w      *bufio.Writer
+	csv    *csv.Writer
+	pages  int
+	err    error // The first write error, after which nothing else is written.
+	lock   sync.Mutex
+}
+
+func NewStreamSink(w io.Writer, format string) (*StreamSink, error) {
+	s := &StreamSink{CountingSink: crawler.NewCountingSink(), format: format, w: bufio.NewWriter(w)}
+
+	switch format {
+	case FormatNDJSON:
+	case FormatCSV:
+		s.csv = csv.NewWriter(s.w)
+		s.err = s.csv.Write(csvHeader)
+	case FormatJSON:
+		_, s.err = io.WriteString(s.w, "{\n  \"pages\": [")
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+
+	return s, nil
+}
+
+func (s *StreamSink) Record(r *crawler.PageResult) {
+	s.CountingSink.Record(r)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return
+	}
+
+	rec := NewRecord(r)
+	switch s.format {
+	case FormatNDJSON:
+		s.err = json.NewEncoder(s.w).Encode(rec)
+	case FormatCSV:
+		s.err = s.csv.Write(rec.csvRow())
+	case FormatJSON:
+		s.err = s.writeJSONPage(rec)
+	}
+	s.pages++
+}
+
+// writeJSONPage writes a page indented the same way as WriteJSON does.
+func (s *StreamSink) writeJSONPage(rec Record) error {
+	b, err := json.MarshalIndent(rec, "    ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n    "
+	if s.pages == 0 {
+		sep = "\n    "
+	}
+	_, err = io.WriteString(s.w, sep+string(b))
+	return err
+}
+
+// Close writes out whatever is left of the output. The summary is only part of JSON output.
+func (s *StreamSink) Close(summary Summary) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.err != nil {
+		return s.err
+	}
+
+	switch s.format {
+	case FormatCSV:
+		s.csv.Flush()
+		if err := s.csv.Error(); err != nil {
+			return err
+		}
+	case FormatJSON:
+		b, err := json.MarshalIndent(summary, "  ", "  ")
+		if err != nil {
+			return err
+		}
+		end := "\n  ],\n"
+		if s.pages == 0 {
+			end = "],\n"
+		}
+		if _, err := io.WriteString(s.w, end+"  \"summary\": "+string(b)+"\n}\n"); err != nil {
+			return err
+		}
+	}
+
+	return s.w.Flush()
+}
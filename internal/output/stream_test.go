@@ -0,0 +1,66 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+	"webcrawler-go/internal/crawler"
+)
+
+func TestStreamSink(t *testing.T) {
+	startedAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	stopErr := errors.New("context deadline exceeded")
+	summary := NewSummary(startedAt, 3*time.Second, testResults(), stopErr)
+
+	stream := func(t *testing.T, format string, results []*crawler.PageResult) (*StreamSink, string) {
+		var b bytes.Buffer
+		s, err := NewStreamSink(&b, format)
+		require.NoError(t, err)
+		for _, r := range results {
+			s.Record(r)
+		}
+		require.NoError(t, s.Close(NewSummaryFromCounts(startedAt, 3*time.Second, s.Counts(), stopErr)))
+		return s, b.String()
+	}
+
+	for _, format := range []string{FormatNDJSON, FormatCSV} {
+		t.Run("writes the same "+format+" as Write", func(t *testing.T) {
+			var want bytes.Buffer
+			require.NoError(t, Write(&want, format, summary, testResults()))
+
+			_, got := stream(t, format, testResults())
+			assert.Equal(t, want.String(), got)
+		})
+	}
+
+	t.Run("writes the same JSON as Write, with the summary last", func(t *testing.T) {
+		var want bytes.Buffer
+		require.NoError(t, Write(&want, FormatJSON, summary, testResults()))
+
+		s, got := stream(t, FormatJSON, testResults())
+		assert.JSONEq(t, want.String(), got)
+		assert.Equal(t, 3, s.Len())
+		assert.Equal(t, 1, s.Count(crawler.PageFailed))
+	})
+
+	t.Run("writes valid JSON without any pages", func(t *testing.T) {
+		_, got := stream(t, FormatJSON, nil)
+
+		var doc struct {
+			Pages   []Record `json:"pages"`
+			Summary Summary  `json:"summary"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(got), &doc))
+		assert.Empty(t, doc.Pages)
+		assert.Zero(t, doc.Summary.Pages)
+	})
+
+	t.Run("rejects unknown formats", func(t *testing.T) {
+		_, err := NewStreamSink(&bytes.Buffer{}, "xml")
+		assert.ErrorContains(t, err, `unknown output format "xml"`)
+	})
+}
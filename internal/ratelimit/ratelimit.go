@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"net/url"
+	"sync"
+	"time"
+)
+
+type ILimiter interface {
+	Wait(ctx context.Context, targetUrl string) error
+}
+
+// Limiter is a per-host token bucket shared by every crawl worker. Each host gets its own bucket that refills at rps tokens per
+// second and holds up to burst tokens. On top of that, consecutive requests to a host are always spaced out by at least
+// minDelay, or by the host's robots.txt Crawl-delay if that's longer.
+type Limiter struct {
+	rps       float64
+	burst     int
+	minDelay  time.Duration
+	delayFunc func(ctx context.Context, targetUrl string) time.Duration
+	hosts     map[string]*bucket
+	lock      sync.Mutex
+
+	now func() time.Time
+}
+
+// bucket tracks a host's token bucket as the theoretical time at which it will be full again (see GCRA),
+// which lets callers reserve a slot up-front and sleep without holding the lock.
+type bucket struct {
+	full time.Time // when the bucket will be back to holding burst tokens
+	last time.Time // when the most recent request was scheduled for
+}
+
+type options struct {
+	burst     int
+	minDelay  time.Duration
+	delayFunc func(ctx context.Context, targetUrl string) time.Duration
+}
+
+type Option func(o *options)
+
+// WithBurst lets a host take up to n requests back-to-back before the rate limit kicks in. Defaults to 1.
+func WithBurst(n int) Option {
+	return func(o *options) {
+		o.burst = n
+	}
+}
+
+// WithMinDelay spaces out consecutive requests to the same host by at least d, regardless of the burst.
+func WithMinDelay(d time.Duration) Option {
+	return func(o *options) {
+		o.minDelay = d
+	}
+}
+
+// WithDelayFunc looks up a per-host minimum delay for each request, e.g. robots.txt's Crawl-delay.
+func WithDelayFunc(fn func(ctx context.Context, targetUrl string) time.Duration) Option {
+	return func(o *options) {
+		o.delayFunc = fn
+	}
+}
+
+// NewLimiter builds a limiter allowing rps requests per second per host. A non-positive rps leaves the rate unbounded,
+// in which case only the min delay and delay func apply.
+func NewLimiter(rps float64, opts ...Option) *Limiter {
+	o := &options{burst: 1}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.burst < 1 {
+		o.burst = 1
+	}
+
+	return &Limiter{
+		rps:       rps,
+		burst:     o.burst,
+		minDelay:  o.minDelay,
+		delayFunc: o.delayFunc,
+		hosts:     make(map[string]*bucket),
+		now:       time.Now,
+	}
+}
+
+// Wait blocks until the URL's host is allowed another request, or until ctx is done.
+// The slot stays reserved when ctx is done early, which errs on the side of politeness.
+func (l *Limiter) Wait(ctx context.Context, targetUrl string) error {
+	d := l.reserve(ctx, targetUrl)
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// reserve books the next free slot for the URL's host and returns how long the caller has to wait for it.
+func (l *Limiter) reserve(ctx context.Context, targetUrl string) time.Duration {
+	host := targetUrl
+	if u, err := url.Parse(targetUrl); err == nil && u.Host != "" {
+		host = u.Host
+	}
+
+	// Looked up before taking the lock, as it may need to fetch the host's robots.txt.
+	minDelay := l.minDelay
+	if l.delayFunc != nil {
+		if d := l.delayFunc(ctx, targetUrl); d > minDelay {
+			minDelay = d
+		}
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	now := l.now()
+
+	b, ok := l.hosts[host]
+	if !ok {
+		b = &bucket{}
+		l.hosts[host] = b
+	}
+
+	start := now
+
+	var interval time.Duration
+	if l.rps > 0 {
+		interval = time.Duration(float64(time.Second) / l.rps)
+
+		// A token is available once the bucket is at most burst-1 intervals away from being full.
+		if earliest := b.full.Add(-time.Duration(l.burst-1) * interval); earliest.After(start) {
+			start = earliest
+		}
+	}
+
+	if ok {
+		if earliest := b.last.Add(minDelay); earliest.After(start) {
+			start = earliest
+		}
+	}
+
+	if b.full.Before(start) {
+		b.full = start
+	}
+	b.full = b.full.Add(interval)
+	b.last = start
+
+	return start.Sub(now)
+}
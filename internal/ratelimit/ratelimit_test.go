@@ -0,0 +1,114 @@
+package ratelimit
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestLimiter returns a limiter on a fake clock that only moves when advanced.
+func newTestLimiter(rps float64, opts ...Option) (*Limiter, *time.Time) {
+	now := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewLimiter(rps, opts...)
+	l.now = func() time.Time { return now }
+	return l, &now
+}
+
+func TestLimiter_reserve(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("spaces out requests to the same host", func(t *testing.T) {
+		l, _ := newTestLimiter(10)
+
+		assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://monzo.com/"))
+		assert.Equal(t, 100*time.Millisecond, l.reserve(ctx, "https://monzo.com/help/"))
+		assert.Equal(t, 200*time.Millisecond, l.reserve(ctx, "https://monzo.com/switch/"))
+	})
+
+	t.Run("keeps a bucket per host", func(t *testing.T) {
+		l, _ := newTestLimiter(1)
+
+		assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://monzo.com/"))
+		assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://google.com/"))
+		assert.Equal(t, time.Second, l.reserve(ctx, "https://monzo.com/help/"))
+		assert.Equal(t, time.Second, l.reserve(ctx, "https://google.com/search"))
+	})
+
+	t.Run("refills the bucket over time", func(t *testing.T) {
+		l, now := newTestLimiter(2, WithBurst(3))
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://monzo.com/"), "burst %d", i)
+		}
+		assert.Equal(t, 500*time.Millisecond, l.reserve(ctx, "https://monzo.com/"))
+
+		*now = now.Add(5 * time.Second)
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://monzo.com/"), "burst %d", i)
+		}
+	})
+
+	t.Run("enforces the min delay on top of the rate", func(t *testing.T) {
+		l, _ := newTestLimiter(100, WithBurst(5), WithMinDelay(time.Second))
+
+		assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://monzo.com/"))
+		assert.Equal(t, time.Second, l.reserve(ctx, "https://monzo.com/"))
+		assert.Equal(t, 2*time.Second, l.reserve(ctx, "https://monzo.com/"))
+	})
+
+	t.Run("honours the delay func when it's longer", func(t *testing.T) {
+		crawlDelays := map[string]time.Duration{"https://slow.com/": 3 * time.Second}
+		l, _ := newTestLimiter(0, WithMinDelay(time.Second), WithDelayFunc(func(ctx context.Context, targetUrl string) time.Duration {
+			return crawlDelays[targetUrl]
+		}))
+
+		assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://slow.com/"))
+		assert.Equal(t, 3*time.Second, l.reserve(ctx, "https://slow.com/"))
+		assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://fast.com/"))
+		assert.Equal(t, time.Second, l.reserve(ctx, "https://fast.com/"))
+	})
+
+	t.Run("is unbounded by default", func(t *testing.T) {
+		l, _ := newTestLimiter(0)
+
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, time.Duration(0), l.reserve(ctx, "https://monzo.com/"))
+		}
+	})
+}
+
+func TestLimiter_Wait(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("is shared safely by concurrent workers", func(t *testing.T) {
+		l := NewLimiter(50)
+
+		start := time.Now()
+		var wg sync.WaitGroup
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.NoError(t, l.Wait(ctx, "https://monzo.com/"))
+			}()
+		}
+		wg.Wait()
+
+		// 5 requests at 50 rps take at least 4 intervals of 20ms.
+		assert.GreaterOrEqual(t, time.Since(start), 80*time.Millisecond)
+	})
+
+	t.Run("stops waiting when the context is done", func(t *testing.T) {
+		l := NewLimiter(0, WithMinDelay(time.Hour))
+		assert.NoError(t, l.Wait(ctx, "https://monzo.com/"))
+
+		ctx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		assert.ErrorIs(t, l.Wait(ctx, "https://monzo.com/"), context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+}
@@ -0,0 +1,25 @@
+package robots
+
+import (
+	"context"
+	"time"
+)
+
+// MockRobots disallows every URL that it holds.
+type MockRobots map[string]bool
+
+func NewMockRobots(disallowed ...string) MockRobots {
+	m := MockRobots{}
+	for _, u := range disallowed {
+		m[u] = true
+	}
+	return m
+}
+
+func (r MockRobots) Allowed(ctx context.Context, targetUrl string) bool {
+	return !r[targetUrl]
+}
+
+func (r MockRobots) CrawlDelay(ctx context.Context, targetUrl string) time.Duration {
+	return 0
+}
@@ -0,0 +1,178 @@
+package robots
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rules holds the directives of the robots.txt group that applies to our user-agent.
+type rules struct {
+	allow      []string
+	disallow   []string
+	crawlDelay time.Duration
+	sitemaps   []string // Sitemap lines apply to every user-agent, wherever they appear in the file.
+}
+
+// group is a set of directives shared by one or more consecutive user-agent lines.
+type group struct {
+	agents []string
+	rules
+}
+
+var (
+	allowAll    = &rules{}
+	disallowAll = &rules{disallow: []string{"/"}}
+)
+
+// parse reads a robots.txt body as described in RFC 9309 and returns the rules for the given product token (e.g. "webcrawler-go").
+// Groups naming the product token take precedence over the "*" group; multiple matching groups are merged.
+func parse(content string, productToken string) *rules {
+	var (
+		groups   []*group
+		current  *group
+		inRules  bool // a user-agent line that follows a rule starts a new group
+		sitemaps []string
+	)
+
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := scanner.Text()
+		if i := strings.IndexByte(line, '#'); i >= 0 {
+			line = line[:i]
+		}
+
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		key = strings.ToLower(strings.TrimSpace(key))
+		value = strings.TrimSpace(value)
+
+		switch key {
+		case "sitemap":
+			if value != "" {
+				sitemaps = append(sitemaps, value)
+			}
+		case "user-agent":
+			if current == nil || inRules {
+				current = &group{}
+				groups = append(groups, current)
+				inRules = false
+			}
+			current.agents = append(current.agents, strings.ToLower(value))
+		case "allow", "disallow", "crawl-delay":
+			// Rules outside of a group are invalid and ignored.
+			if current == nil {
+				continue
+			}
+			inRules = true
+
+			switch key {
+			case "allow":
+				if value != "" {
+					current.allow = append(current.allow, value)
+				}
+			case "disallow":
+				// An empty disallow means "allow everything", which is the default anyway.
+				if value != "" {
+					current.disallow = append(current.disallow, value)
+				}
+			case "crawl-delay":
+				if secs, err := strconv.ParseFloat(value, 64); err == nil && secs > 0 {
+					current.crawlDelay = time.Duration(secs * float64(time.Second))
+				}
+			}
+		}
+	}
+
+	productToken = strings.ToLower(productToken)
+
+	var matched, wildcard []*group
+	for _, g := range groups {
+		for _, a := range g.agents {
+			if a == productToken {
+				matched = append(matched, g)
+				break
+			}
+			if a == "*" {
+				wildcard = append(wildcard, g)
+				break
+			}
+		}
+	}
+
+	if len(matched) == 0 {
+		matched = wildcard
+	}
+
+	r := &rules{sitemaps: sitemaps}
+	for _, g := range matched {
+		r.allow = append(r.allow, g.allow...)
+		r.disallow = append(r.disallow, g.disallow...)
+		if g.crawlDelay > r.crawlDelay {
+			r.crawlDelay = g.crawlDelay
+		}
+	}
+
+	return r
+}
+
+// allowed reports whether the given path (including its query string) may be crawled.
+// The most specific (longest) matching rule wins, and allow wins over disallow on a tie.
+func (r *rules) allowed(path string) bool {
+	if path == "/robots.txt" {
+		return true
+	}
+
+	longestAllow, longestDisallow := -1, -1
+	for _, p := range r.allow {
+		if len(p) > longestAllow && match(p, path) {
+			longestAllow = len(p)
+		}
+	}
+	for _, p := range r.disallow {
+		if len(p) > longestDisallow && match(p, path) {
+			longestDisallow = len(p)
+		}
+	}
+
+	return longestAllow >= longestDisallow
+}
+
+// match reports whether path matches a robots.txt path pattern, where '*' matches any sequence of characters
+// and a trailing '$' anchors the pattern to the end of the path.
+func match(pattern string, path string) bool {
+	anchored := strings.HasSuffix(pattern, "$")
+	if anchored {
+		pattern = strings.TrimSuffix(pattern, "$")
+	}
+
+	parts := strings.Split(pattern, "*")
+
+	// The first part must be a prefix, as robots.txt patterns always match from the start of the path.
+	if !strings.HasPrefix(path, parts[0]) {
+		return false
+	}
+	rest := path[len(parts[0]):]
+
+	for i, part := range parts[1:] {
+		last := i == len(parts)-2
+		if last && anchored {
+			return strings.HasSuffix(rest, part)
+		}
+
+		idx := strings.Index(rest, part)
+		if idx < 0 {
+			return false
+		}
+		rest = rest[idx+len(part):]
+	}
+
+	if anchored && len(parts) == 1 {
+		return rest == ""
+	}
+
+	return true
+}
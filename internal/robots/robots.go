@@ -0,0 +1,193 @@
+package robots
+
+import (
+	"context"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RFC 9309 asks crawlers to parse at least the first 500 KiB of a robots.txt file.
+const maxRobotsSize = 500 * 1024
+
+type IRobots interface {
+	Allowed(ctx context.Context, targetUrl string) bool
+	CrawlDelay(ctx context.Context, targetUrl string) time.Duration
+}
+
+// Robots fetches and caches the robots.txt rules of every host the crawler comes across.
+// Each host's robots.txt is only fetched once, even when many workers ask for it at the same time. A host whose robots.txt
+// is temporarily unreachable is disallowed for a while, after which its robots.txt is fetched again.
+type Robots struct {
+	client     *http.Client
+	userAgent  string
+	retryAfter time.Duration
+	hosts      map[string]*hostRules
+	lock       sync.Mutex
+}
+
+type hostRules struct {
+	ready   chan struct{} // closed once rules and expires have been populated
+	rules   *rules
+	expires time.Time // when the rules should be fetched again, or zero if they're good for the rest of the run
+}
+
+type Option func(r *Robots)
+
+// WithRetryAfter sets how long a host whose robots.txt couldn't be reached, e.g. because of a 503 or a network error, is
+// disallowed for before its robots.txt is fetched again. It's a minute by default.
+func WithRetryAfter(d time.Duration) Option {
+	return func(r *Robots) {
+		r.retryAfter = d
+	}
+}
+
+func NewRobots(client *http.Client, userAgent string, opts ...Option) *Robots {
+	r := &Robots{
+		client:     client,
+		userAgent:  userAgent,
+		retryAfter: time.Minute,
+		hosts:      make(map[string]*hostRules),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	return r
+}
+
+// Allowed reports whether the robots.txt of the URL's host lets our user-agent crawl it.
+// URLs that can't be parsed are let through so that the fetcher can report the actual error.
+func (r *Robots) Allowed(ctx context.Context, rawTargetUrl string) bool {
+	targetUrl, err := url.Parse(rawTargetUrl)
+	if err != nil {
+		return true
+	}
+
+	return r.rulesFor(ctx, targetUrl).allowed(targetUrl.RequestURI())
+}
+
+// CrawlDelay returns the Crawl-delay that the URL's host asks for, or zero if there is none.
+func (r *Robots) CrawlDelay(ctx context.Context, rawTargetUrl string) time.Duration {
+	targetUrl, err := url.Parse(rawTargetUrl)
+	if err != nil {
+		return 0
+	}
+
+	return r.rulesFor(ctx, targetUrl).crawlDelay
+}
+
+// Sitemaps returns the sitemaps listed in the robots.txt of the URL's host, if any.
+func (r *Robots) Sitemaps(ctx context.Context, rawTargetUrl string) []string {
+	targetUrl, err := url.Parse(rawTargetUrl)
+	if err != nil {
+		return nil
+	}
+
+	return r.rulesFor(ctx, targetUrl).sitemaps
+}
+
+// rulesFor returns the cached rules of the URL's host, fetching them first if this is the first time the host comes up or
+// if its cached rules have expired. Callers whose ctx is done while another caller is still fetching the rules get disallowed.
+func (r *Robots) rulesFor(ctx context.Context, targetUrl *url.URL) *rules {
+	key := targetUrl.Scheme + "://" + targetUrl.Host
+
+	for {
+		r.lock.Lock()
+		h, ok := r.hosts[key]
+		if !ok || (h.done() && h.expired()) {
+			h = &hostRules{ready: make(chan struct{})}
+			r.hosts[key] = h
+			ok = false
+		}
+		r.lock.Unlock()
+
+		if !ok {
+			rules, temporary := r.fetch(ctx, key+"/robots.txt")
+			h.rules = rules
+			switch {
+			case ctx.Err() != nil:
+				// The fetch was cut short on our end, which says nothing about the host, so the next caller tries again.
+				h.expires = time.Now()
+			case temporary:
+				h.expires = time.Now().Add(r.retryAfter)
+			}
+			close(h.ready)
+
+			return h.rules
+		}
+
+		select {
+		case <-h.ready:
+			if !h.expired() {
+				return h.rules
+			}
+		case <-ctx.Done():
+			return disallowAll
+		}
+	}
+}
+
+// done reports whether the rules have been fetched.
+func (h *hostRules) done() bool {
+	select {
+	case <-h.ready:
+		return true
+	default:
+		return false
+	}
+}
+
+// expired reports whether the rules should be fetched again. It must only be called once ready is closed.
+func (h *hostRules) expired() bool {
+	return !h.expires.IsZero() && !time.Now().Before(h.expires)
+}
+
+// fetch downloads and parses a robots.txt file. Following RFC 9309, a missing file (4xx) allows everything,
+// whereas an unreachable file (5xx or network errors) disallows everything. It also reports whether the failure was
+// temporary, in which case the file is worth fetching again later.
+func (r *Robots) fetch(ctx context.Context, robotsUrl string) (*rules, bool) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, robotsUrl, nil)
+	if err != nil {
+		log.Printf("unable to build request for %s - %v\n", robotsUrl, err)
+		return disallowAll, false
+	}
+	req.Header.Set("User-Agent", r.userAgent)
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		log.Printf("unable to fetch %s, treating host as disallowed - %v\n", robotsUrl, err)
+		return disallowAll, true
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 500:
+		log.Printf("unable to fetch %s, treating host as disallowed - status %d\n", robotsUrl, resp.StatusCode)
+		return disallowAll, true
+	case resp.StatusCode >= 400:
+		return allowAll, false
+	case resp.StatusCode >= 300:
+		// The client follows redirects for us, so this is a redirect it gave up on.
+		return allowAll, false
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, maxRobotsSize))
+	if err != nil {
+		log.Printf("unable to read %s, treating host as disallowed - %v\n", robotsUrl, err)
+		return disallowAll, true
+	}
+
+	return parse(string(content), productToken(r.userAgent)), false
+}
+
+// productToken extracts the name that robots.txt groups refer to from a full user-agent, e.g. "webcrawler-go/1.0" -> "webcrawler-go".
+func productToken(userAgent string) string {
+	token, _, _ := strings.Cut(userAgent, "/")
+	token, _, _ = strings.Cut(token, " ")
+	return strings.TrimSpace(token)
+}
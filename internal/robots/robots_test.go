@@ -0,0 +1,241 @@
+package robots
+
+import (
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRobots_parse(t *testing.T) {
+	t.Run("when there is a group for our user-agent", func(t *testing.T) {
+		r := parse(`
+User-agent: *
+Disallow: /
+
+# Our own group takes precedence over the wildcard one.
+User-agent: otherbot
+User-agent: WebCrawler-Go
+Disallow: /private/
+Crawl-delay: 2.5
+`, "webcrawler-go")
+
+		assert.True(t, r.allowed("/"))
+		assert.True(t, r.allowed("/public/"))
+		assert.False(t, r.allowed("/private/page"))
+		assert.Equal(t, 2500*time.Millisecond, r.crawlDelay)
+	})
+
+	t.Run("when only the wildcard group applies", func(t *testing.T) {
+		r := parse(`
+User-agent: otherbot
+Disallow: /
+
+User-agent: *
+Disallow: /admin
+`, "webcrawler-go")
+
+		assert.True(t, r.allowed("/"))
+		assert.False(t, r.allowed("/admin"))
+		assert.False(t, r.allowed("/admin/users"))
+		assert.Zero(t, r.crawlDelay)
+	})
+
+	t.Run("when groups for our user-agent are split up", func(t *testing.T) {
+		r := parse(`
+User-agent: webcrawler-go
+Disallow: /a/
+
+User-agent: *
+Disallow: /b/
+
+User-agent: webcrawler-go
+Disallow: /c/
+`, "webcrawler-go")
+
+		assert.False(t, r.allowed("/a/"))
+		assert.True(t, r.allowed("/b/"))
+		assert.False(t, r.allowed("/c/"))
+	})
+
+	t.Run("when no group applies", func(t *testing.T) {
+		r := parse(`
+User-agent: otherbot
+Disallow: /
+`, "webcrawler-go")
+
+		assert.True(t, r.allowed("/anything"))
+	})
+
+	t.Run("when the file is malformed", func(t *testing.T) {
+		r := parse(`
+Disallow: /orphaned-rule
+this line is garbage
+User-agent: *
+Disallow /missing-colon
+Disallow:
+Crawl-delay: soon
+`, "webcrawler-go")
+
+		assert.True(t, r.allowed("/orphaned-rule"))
+		assert.True(t, r.allowed("/missing-colon"))
+		assert.Zero(t, r.crawlDelay)
+	})
+
+	t.Run("when allow and disallow rules overlap", func(t *testing.T) {
+		r := parse(`
+User-agent: *
+Disallow: /docs/
+Allow: /docs/public/
+Allow: /page
+Disallow: /page
+`, "webcrawler-go")
+
+		assert.False(t, r.allowed("/docs/secret"))
+		assert.True(t, r.allowed("/docs/public/intro"))
+		assert.True(t, r.allowed("/page"), "allow wins on a tie")
+	})
+
+	t.Run("when rules use wildcards and end anchors", func(t *testing.T) {
+		r := parse(`
+User-agent: *
+Disallow: /*.pdf$
+Disallow: /search*q=
+Disallow: /exact$
+Disallow: /*/edit
+`, "webcrawler-go")
+
+		assert.False(t, r.allowed("/files/report.pdf"))
+		assert.True(t, r.allowed("/files/report.pdf?download=1"))
+		assert.False(t, r.allowed("/search?lang=en&q=cards"))
+		assert.True(t, r.allowed("/search?lang=en"))
+		assert.False(t, r.allowed("/exact"))
+		assert.True(t, r.allowed("/exact/more"))
+		assert.False(t, r.allowed("/posts/1/edit"))
+		assert.True(t, r.allowed("/edit"))
+	})
+
+	t.Run("when sitemaps are listed", func(t *testing.T) {
+		r := parse(`
+Sitemap: https://monzo.com/sitemap.xml
+User-agent: otherbot
+Disallow: /
+sitemap: https://monzo.com/blog/sitemap.xml.gz # inside another bot's group
+Sitemap:
+`, "webcrawler-go")
+
+		assert.Equal(t, []string{"https://monzo.com/sitemap.xml", "https://monzo.com/blog/sitemap.xml.gz"}, r.sitemaps)
+		assert.True(t, r.allowed("/"))
+	})
+
+	t.Run("when robots.txt itself is disallowed", func(t *testing.T) {
+		r := parse(`
+User-agent: *
+Disallow: /
+`, "webcrawler-go")
+
+		assert.True(t, r.allowed("/robots.txt"))
+	})
+}
+
+func TestRobots_Allowed(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("fetches robots.txt once per host", func(t *testing.T) {
+		var hits atomic.Int64
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/robots.txt" {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			hits.Add(1)
+			assert.Equal(t, "webcrawler-go/1.0", r.Header.Get("User-Agent"))
+			fmt.Fprint(w, "User-agent: webcrawler-go\nDisallow: /private/\nCrawl-delay: 1\n")
+		}))
+		defer testServer.Close()
+
+		r := NewRobots(testServer.Client(), "webcrawler-go/1.0")
+
+		var wg sync.WaitGroup
+		for i := 0; i < 10; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				assert.True(t, r.Allowed(ctx, testServer.URL+"/public/"))
+				assert.False(t, r.Allowed(ctx, testServer.URL+"/private/page"))
+			}()
+		}
+		wg.Wait()
+
+		assert.Equal(t, time.Second, r.CrawlDelay(ctx, testServer.URL+"/"))
+		assert.Empty(t, r.Sitemaps(ctx, testServer.URL+"/"))
+		assert.Equal(t, int64(1), hits.Load())
+	})
+
+	t.Run("when robots.txt is missing", func(t *testing.T) {
+		testServer := httptest.NewServer(http.NotFoundHandler())
+		defer testServer.Close()
+
+		r := NewRobots(testServer.Client(), "webcrawler-go/1.0")
+		assert.True(t, r.Allowed(ctx, testServer.URL+"/anything"))
+	})
+
+	t.Run("when robots.txt is unavailable", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer testServer.Close()
+
+		r := NewRobots(testServer.Client(), "webcrawler-go/1.0")
+		assert.False(t, r.Allowed(ctx, testServer.URL+"/anything"))
+	})
+
+	t.Run("fetches robots.txt again once it's back after being unavailable", func(t *testing.T) {
+		var hits atomic.Int64
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if hits.Add(1) == 1 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		}))
+		defer testServer.Close()
+
+		r := NewRobots(testServer.Client(), "webcrawler-go/1.0", WithRetryAfter(50*time.Millisecond))
+		assert.False(t, r.Allowed(ctx, testServer.URL+"/public/"))
+		assert.False(t, r.Allowed(ctx, testServer.URL+"/public/"), "should stay disallowed until the retry is due")
+		assert.Equal(t, int64(1), hits.Load())
+
+		time.Sleep(60 * time.Millisecond)
+		assert.True(t, r.Allowed(ctx, testServer.URL+"/public/"))
+		assert.False(t, r.Allowed(ctx, testServer.URL+"/private/"))
+		assert.Equal(t, int64(2), hits.Load())
+	})
+
+	t.Run("doesn't hold a cancelled fetch against the host", func(t *testing.T) {
+		var hits atomic.Int64
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits.Add(1)
+			fmt.Fprint(w, "User-agent: *\nDisallow: /private/\n")
+		}))
+		defer testServer.Close()
+
+		r := NewRobots(testServer.Client(), "webcrawler-go/1.0")
+		cancelled, cancel := context.WithCancel(ctx)
+		cancel()
+		assert.False(t, r.Allowed(cancelled, testServer.URL+"/public/"))
+
+		assert.True(t, r.Allowed(ctx, testServer.URL+"/public/"))
+		assert.Equal(t, int64(1), hits.Load())
+	})
+
+	t.Run("when the host is unreachable", func(t *testing.T) {
+		r := NewRobots(http.DefaultClient, "webcrawler-go/1.0")
+		assert.False(t, r.Allowed(ctx, "https://localhost.org/anything"))
+	})
+}
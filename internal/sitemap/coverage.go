@@ -0,0 +1,69 @@
+package sitemap
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+)
+
+const (
+	MissingFromCrawl   = "crawl"
+	MissingFromSitemap = "sitemap"
+)
+
+// Coverage compares the pages listed in a site's sitemaps with the pages that the crawler reached by following links.
+type Coverage struct {
+	SitemapOnly []string // Listed in the sitemaps, but not linked to from any crawled page.
+	CrawlOnly   []string // Crawled and eligible for a sitemap (see FromResults), but not listed in the sitemaps.
+}
+
+// NewCoverage compares the sitemaps' pages with the pages that were linked to and the pages that were crawled.
+func NewCoverage(listed []URL, linked []string, crawled []URL) *Coverage {
+	inSitemap := make(map[string]bool, len(listed))
+	for _, u := range listed {
+		inSitemap[u.Loc] = true
+	}
+
+	isLinked := make(map[string]bool, len(linked))
+	for _, u := range linked {
+		isLinked[u] = true
+	}
+
+	c := &Coverage{SitemapOnly: make([]string, 0), CrawlOnly: make([]string, 0)}
+	for u := range inSitemap {
+		if !isLinked[u] {
+			c.SitemapOnly = append(c.SitemapOnly, u)
+		}
+	}
+	for _, u := range crawled {
+		if !inSitemap[u.Loc] {
+			c.CrawlOnly = append(c.CrawlOnly, u.Loc)
+		}
+	}
+
+	sort.Strings(c.SitemapOnly)
+	sort.Strings(c.CrawlOnly)
+
+	return c
+}
+
+// WriteCSV writes a header row followed by one row per page that's missing from either side.
+func (c *Coverage) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"url", "missingFrom"}); err != nil {
+		return err
+	}
+	for _, u := range c.SitemapOnly {
+		if err := cw.Write([]string{u, MissingFromCrawl}); err != nil {
+			return err
+		}
+	}
+	for _, u := range c.CrawlOnly {
+		if err := cw.Write([]string{u, MissingFromSitemap}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+
+	return cw.Error()
+}
@@ -0,0 +1,187 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Sitemap indexes may only point to sitemaps, not to other indexes, so there are at most two levels to read.
+const maxIndexDepth = 2
+
+// Reader downloads sitemaps and sitemap indexes, gzipped or not, and collects the pages listed in them.
+type Reader struct {
+	client    *http.Client
+	userAgent string
+}
+
+func NewReader(client *http.Client, userAgent string) *Reader {
+	return &Reader{
+		client:    client,
+		userAgent: userAgent,
+	}
+}
+
+// document is either a <urlset> or a <sitemapindex>.
+type document struct {
+	XMLName xml.Name
+	URLs    []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+	Sitemaps []struct {
+		Loc string `xml:"loc"`
+	} `xml:"sitemap"`
+}
+
+// Discover reads the sitemaps of the site that siteUrl belongs to: the ones listed in its robots.txt, given as listed, as well
+// as /sitemap.xml. Sitemaps that can't be read are logged and skipped, so that one broken sitemap doesn't hide the others.
+func (r *Reader) Discover(ctx context.Context, siteUrl string, listed []string) ([]URL, error) {
+	u, err := url.Parse(siteUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	sitemapUrls := append(append([]string(nil), listed...), u.Scheme+"://"+u.Host+"/sitemap.xml")
+
+	var (
+		urls []URL
+		seen = make(map[string]bool)
+	)
+	for _, sitemapUrl := range sitemapUrls {
+		found, err := r.read(ctx, sitemapUrl, 0, seen)
+		if err != nil {
+			log.Printf("skipping - unable to read sitemap %s - %v\n", sitemapUrl, err)
+			continue
+		}
+		urls = append(urls, found...)
+	}
+
+	return dedup(urls), nil
+}
+
+// Read returns every page listed in the sitemap, following it through if it's an index.
+func (r *Reader) Read(ctx context.Context, sitemapUrl string) ([]URL, error) {
+	urls, err := r.read(ctx, sitemapUrl, 0, make(map[string]bool))
+	if err != nil {
+		return nil, err
+	}
+	return dedup(urls), nil
+}
+
+func (r *Reader) read(ctx context.Context, sitemapUrl string, depth int, seen map[string]bool) ([]URL, error) {
+	if seen[sitemapUrl] {
+		return nil, nil
+	}
+	seen[sitemapUrl] = true
+
+	doc, err := r.fetch(ctx, sitemapUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	switch doc.XMLName.Local {
+	case "urlset":
+		urls := make([]URL, 0, len(doc.URLs))
+		for _, u := range doc.URLs {
+			// Sitemaps are often indented, and whitespace around a <loc> isn't part of the URL.
+			if loc := strings.TrimSpace(u.Loc); loc != "" {
+				urls = append(urls, URL{Loc: loc, LastMod: parseLastMod(strings.TrimSpace(u.LastMod))})
+			}
+		}
+		return urls, nil
+	case "sitemapindex":
+		if depth+1 >= maxIndexDepth {
+			return nil, fmt.Errorf("sitemap indexes are nested more than %d deep", maxIndexDepth)
+		}
+
+		var urls []URL
+		for _, s := range doc.Sitemaps {
+			loc := strings.TrimSpace(s.Loc)
+			found, err := r.read(ctx, loc, depth+1, seen)
+			if err != nil {
+				log.Printf("skipping - unable to read sitemap %s - %v\n", loc, err)
+				continue
+			}
+			urls = append(urls, found...)
+		}
+		return urls, nil
+	default:
+		return nil, fmt.Errorf("unexpected root element <%s>", doc.XMLName.Local)
+	}
+}
+
+// fetch downloads and decodes a sitemap. Gzipped sitemaps are recognised by their contents rather than their extension, as
+// servers don't label them consistently.
+func (r *Reader) fetch(ctx context.Context, sitemapUrl string) (*document, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, sitemapUrl, nil)
+	if err != nil {
+		return nil, err
+	}
+	if r.userAgent != "" {
+		req.Header.Set("User-Agent", r.userAgent)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	content, err := io.ReadAll(io.LimitReader(resp.Body, MaxBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(content, []byte{0x1f, 0x8b}) {
+		gz, err := gzip.NewReader(bytes.NewReader(content))
+		if err != nil {
+			return nil, err
+		}
+		// Uncompressed sitemaps can't be bigger than MaxBytes, which also guards against gzip bombs.
+		if content, err = io.ReadAll(io.LimitReader(gz, MaxBytes)); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := &document{}
+	if err := xml.Unmarshal(content, doc); err != nil {
+		return nil, err
+	}
+
+	return doc, nil
+}
+
+// parseLastMod parses the W3C datetime formats that sitemaps use, from a full timestamp down to a plain date.
+func parseLastMod(v string) time.Time {
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04Z07:00", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func dedup(urls []URL) []URL {
+	seen := make(map[string]bool, len(urls))
+	unique := make([]URL, 0, len(urls))
+	for _, u := range urls {
+		if !seen[u.Loc] {
+			seen[u.Loc] = true
+			unique = append(unique, u)
+		}
+	}
+	return unique
+}
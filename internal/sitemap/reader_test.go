@@ -0,0 +1,147 @@
+package sitemap
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func gzipped(t *testing.T, content string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	_, err := gz.Write([]byte(content))
+	require.NoError(t, err)
+	require.NoError(t, gz.Close())
+	return b.Bytes()
+}
+
+func urlsetOf(locs ...string) string {
+	content := urlsetHeader
+	for _, loc := range locs {
+		content += "<url><loc>" + loc + "</loc><lastmod>2024-01-02</lastmod></url>"
+	}
+	return content + urlsetFooter
+}
+
+func sitemapIndexOf(locs ...string) string {
+	content := indexHeader
+	for _, loc := range locs {
+		content += "<sitemap><loc>" + loc + "</loc></sitemap>"
+	}
+	return content + indexFooter
+}
+
+func TestReader(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("discovers listed sitemaps and /sitemap.xml, following indexes", func(t *testing.T) {
+		mux := http.NewServeMux()
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, sitemapIndexOf(testServer.URL+"/pages.xml.gz", testServer.URL+"/missing.xml", testServer.URL+"/blog.xml"))
+		})
+		mux.HandleFunc("/pages.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/gzip")
+			w.Write(gzipped(t, urlsetOf(testServer.URL+"/", testServer.URL+"/about/")))
+		})
+		mux.HandleFunc("/blog.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, urlsetOf(testServer.URL+"/blog/", testServer.URL+"/about/"))
+		})
+		mux.HandleFunc("/news.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, urlsetOf(testServer.URL+"/news/"))
+		})
+
+		reader := NewReader(testServer.Client(), "webcrawler-go/1.0")
+		urls, err := reader.Discover(ctx, testServer.URL+"/start/", []string{testServer.URL + "/news.xml", testServer.URL + "/sitemap.xml"})
+		require.NoError(t, err)
+
+		var locs []string
+		for _, u := range urls {
+			locs = append(locs, u.Loc)
+		}
+		assert.Equal(t, []string{
+			testServer.URL + "/news/",
+			testServer.URL + "/",
+			testServer.URL + "/about/",
+			testServer.URL + "/blog/",
+		}, locs)
+		assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), urls[0].LastMod)
+	})
+
+	t.Run("trims whitespace around locs", func(t *testing.T) {
+		mux := http.NewServeMux()
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, indexHeader+"\n  <sitemap>\n    <loc>\n      "+testServer.URL+"/pages.xml\n    </loc>\n  </sitemap>\n"+indexFooter)
+		})
+		mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, urlsetHeader+"\n  <url>\n    <loc>\n      "+testServer.URL+"/about/\n    </loc>\n    <lastmod> 2024-01-02 </lastmod>\n  </url>\n"+urlsetFooter)
+		})
+
+		urls, err := NewReader(testServer.Client(), "").Read(ctx, testServer.URL+"/sitemap.xml")
+		require.NoError(t, err)
+		assert.Equal(t, []URL{{Loc: testServer.URL + "/about/", LastMod: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}}, urls)
+	})
+
+	t.Run("rejects indexes of indexes", func(t *testing.T) {
+		mux := http.NewServeMux()
+		testServer := httptest.NewServer(mux)
+		defer testServer.Close()
+
+		mux.HandleFunc("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, sitemapIndexOf(testServer.URL+"/nested.xml", testServer.URL+"/sitemap.xml"))
+		})
+		mux.HandleFunc("/nested.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, sitemapIndexOf(testServer.URL+"/pages.xml"))
+		})
+		mux.HandleFunc("/pages.xml", func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, urlsetOf(testServer.URL+"/"))
+		})
+
+		urls, err := NewReader(testServer.Client(), "").Read(ctx, testServer.URL+"/sitemap.xml")
+		require.NoError(t, err)
+		assert.Empty(t, urls)
+	})
+
+	t.Run("fails on sitemaps that aren't sitemaps", func(t *testing.T) {
+		testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			fmt.Fprint(w, `<html><body>Not a sitemap</body></html>`)
+		}))
+		defer testServer.Close()
+
+		_, err := NewReader(testServer.Client(), "").Read(ctx, testServer.URL+"/sitemap.xml")
+		assert.ErrorContains(t, err, "unexpected root element <html>")
+	})
+
+	t.Run("parses lastmod in every W3C datetime format", func(t *testing.T) {
+		assert.Equal(t, time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC), parseLastMod("2024-01-02"))
+		assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 0, 0, time.UTC), parseLastMod("2024-01-02T03:04Z"))
+		assert.True(t, parseLastMod("2024-01-02T03:04:05+01:00").Equal(time.Date(2024, 1, 2, 2, 4, 5, 0, time.UTC)))
+		assert.True(t, parseLastMod("yesterday").IsZero())
+	})
+}
+
+func TestCoverage(t *testing.T) {
+	listed := []URL{{Loc: "https://monzo.com/"}, {Loc: "https://monzo.com/orphan/"}, {Loc: "https://monzo.com/about/"}}
+	linked := []string{"https://monzo.com/", "https://monzo.com/about/", "https://monzo.com/hidden/"}
+	crawled := []URL{{Loc: "https://monzo.com/"}, {Loc: "https://monzo.com/about/"}, {Loc: "https://monzo.com/orphan/"}, {Loc: "https://monzo.com/hidden/"}}
+
+	coverage := NewCoverage(listed, linked, crawled)
+	assert.Equal(t, []string{"https://monzo.com/orphan/"}, coverage.SitemapOnly)
+	assert.Equal(t, []string{"https://monzo.com/hidden/"}, coverage.CrawlOnly)
+
+	var b bytes.Buffer
+	require.NoError(t, coverage.WriteCSV(&b))
+	assert.Equal(t, "url,missingFrom\nhttps://monzo.com/orphan/,crawl\nhttps://monzo.com/hidden/,sitemap\n", b.String())
+}
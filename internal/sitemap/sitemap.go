@@ -0,0 +1,238 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+	"webcrawler-go/internal/crawler"
+	"webcrawler-go/internal/fetcher"
+)
+
+// The limits that a single sitemap file must stay within, as set by https://www.sitemaps.org/protocol.html.
+const (
+	MaxUrls  = 50000
+	MaxBytes = 50 * 1024 * 1024
+)
+
+const (
+	indexFile     = "sitemap.xml"
+	childFile     = "sitemap-%d.xml.gz"
+	urlsetHeader  = xmlHeader + `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	urlsetFooter  = "</urlset>\n"
+	indexHeader   = xmlHeader + `<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">` + "\n"
+	indexFooter   = "</sitemapindex>\n"
+	xmlHeader     = `<?xml version="1.0" encoding="UTF-8"?>` + "\n"
+	lastModFormat = time.RFC3339
+)
+
+// URL is a page to list in the sitemap.
+type URL struct {
+	Loc     string
+	LastMod time.Time // Left out of the sitemap when zero.
+}
+
+// FromResults picks the pages that belong in a sitemap out of a crawl's results: HTML pages that were fetched with a 200,
+// that don't ask to be kept out of search indexes, and that don't declare another page as their canonical version. Pages
+// that were redirected to are listed under the URL they ended up at.
+func FromResults(results []*crawler.PageResult) []URL {
+	seen := make(map[string]bool)
+	urls := make([]URL, 0)
+	for _, r := range results {
+		if r.Status != crawler.PageSucceeded || r.StatusCode != 200 || !fetcher.IsHtml(r.ContentType) || r.NoIndex {
+			continue
+		}
+		loc := r.URL
+		if r.FinalURL != "" {
+			loc = r.FinalURL
+		}
+
+		// Both are normalized by the crawler, so a self-canonical written differently still matches.
+		if r.Canonical != "" && r.Canonical != loc {
+			continue
+		}
+		if seen[loc] {
+			continue
+		}
+		seen[loc] = true
+
+		u := URL{Loc: loc}
+		if r.LastModified != nil {
+			u.LastMod = *r.LastModified
+		}
+		urls = append(urls, u)
+	}
+
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Loc < urls[j].Loc
+	})
+
+	return urls
+}
+
+type Generator struct {
+	maxUrls  int
+	maxBytes int
+}
+
+type Option func(g *Generator)
+
+// WithMaxUrls lowers the no. of URLs per sitemap file from the protocol's limit of 50,000.
+func WithMaxUrls(n int) Option {
+	return func(g *Generator) {
+		g.maxUrls = n
+	}
+}
+
+// WithMaxBytes lowers the uncompressed size of each sitemap file from the protocol's limit of 50 MB.
+func WithMaxBytes(n int) Option {
+	return func(g *Generator) {
+		g.maxBytes = n
+	}
+}
+
+func NewGenerator(opts ...Option) *Generator {
+	g := &Generator{
+		maxUrls:  MaxUrls,
+		maxBytes: MaxBytes,
+	}
+
+	for _, opt := range opts {
+		opt(g)
+	}
+
+	return g
+}
+
+// Write writes the sitemap into dir and returns the paths of the files it wrote. URLs that fit into a single sitemap are
+// written to sitemap.xml. Otherwise, they're split over gzipped sitemap-N.xml.gz files, with sitemap.xml as their index.
+// baseUrl is where dir will be served from, which the index needs to point to the split files.
+func (g *Generator) Write(dir, baseUrl string, urls []URL) ([]string, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+
+	chunks := g.split(urls)
+
+	indexPath := filepath.Join(dir, indexFile)
+	if len(chunks) == 1 {
+		return []string{indexPath}, writeFile(indexPath, false, chunks[0].content())
+	}
+
+	if len(chunks) > MaxUrls {
+		return nil, fmt.Errorf("%d sitemaps is over the limit of %d for a sitemap index", len(chunks), MaxUrls)
+	}
+
+	if !strings.HasSuffix(baseUrl, "/") {
+		baseUrl += "/"
+	}
+
+	var (
+		paths []string
+		index strings.Builder
+	)
+	index.WriteString(indexHeader)
+	for i, c := range chunks {
+		name := fmt.Sprintf(childFile, i+1)
+		path := filepath.Join(dir, name)
+		if err := writeFile(path, true, c.content()); err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+
+		index.WriteString("  <sitemap>\n")
+		index.WriteString("    <loc>" + escape(baseUrl+name) + "</loc>\n")
+		if !c.lastMod.IsZero() {
+			index.WriteString("    <lastmod>" + c.lastMod.UTC().Format(lastModFormat) + "</lastmod>\n")
+		}
+		index.WriteString("  </sitemap>\n")
+	}
+	index.WriteString(indexFooter)
+
+	if err := writeFile(indexPath, false, index.String()); err != nil {
+		return nil, err
+	}
+
+	return append([]string{indexPath}, paths...), nil
+}
+
+// chunk is the contents of a single sitemap file.
+type chunk struct {
+	entries []string
+	size    int
+	lastMod time.Time // The latest lastmod of any of its URLs.
+}
+
+func (c *chunk) content() string {
+	return urlsetHeader + strings.Join(c.entries, "") + urlsetFooter
+}
+
+// split spreads the URLs over as many sitemap files as it takes to keep each one within the limits.
+func (g *Generator) split(urls []URL) []*chunk {
+	emptySize := len(urlsetHeader) + len(urlsetFooter)
+
+	current := &chunk{size: emptySize}
+	chunks := []*chunk{current}
+	for _, u := range urls {
+		e := entry(u)
+		if len(current.entries) > 0 && (len(current.entries) >= g.maxUrls || current.size+len(e) > g.maxBytes) {
+			current = &chunk{size: emptySize}
+			chunks = append(chunks, current)
+		}
+
+		current.entries = append(current.entries, e)
+		current.size += len(e)
+		if u.LastMod.After(current.lastMod) {
+			current.lastMod = u.LastMod
+		}
+	}
+
+	return chunks
+}
+
+func entry(u URL) string {
+	var b strings.Builder
+	b.WriteString("  <url>\n")
+	b.WriteString("    <loc>" + escape(u.Loc) + "</loc>\n")
+	if !u.LastMod.IsZero() {
+		b.WriteString("    <lastmod>" + u.LastMod.UTC().Format(lastModFormat) + "</lastmod>\n")
+	}
+	b.WriteString("  </url>\n")
+	return b.String()
+}
+
+// escape escapes the characters that the sitemap protocol requires to be entity-escaped.
+func escape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "'", "&apos;", `"`, "&quot;", ">", "&gt;", "<", "&lt;").Replace(s)
+}
+
+func writeFile(path string, compress bool, content string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if compress {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+
+	if _, err := io.WriteString(w, content); err != nil {
+		f.Close()
+		return err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	return f.Close()
+}
@@ -0,0 +1,154 @@
+package sitemap
+
+import (
+	"compress/gzip"
+	"encoding/xml"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+	"webcrawler-go/internal/crawler"
+)
+
+type urlset struct {
+	URLs []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"url"`
+}
+
+type sitemapindex struct {
+	Sitemaps []struct {
+		Loc     string `xml:"loc"`
+		LastMod string `xml:"lastmod"`
+	} `xml:"sitemap"`
+}
+
+func readFile(t *testing.T, path string) []byte {
+	f, err := os.Open(path)
+	require.NoError(t, err)
+	defer f.Close()
+
+	var r io.Reader = f
+	if filepath.Ext(path) == ".gz" {
+		gz, err := gzip.NewReader(f)
+		require.NoError(t, err)
+		r = gz
+	}
+
+	content, err := io.ReadAll(r)
+	require.NoError(t, err)
+	return content
+}
+
+func testUrls(n int) []URL {
+	urls := make([]URL, n)
+	for i := range urls {
+		urls[i] = URL{Loc: fmt.Sprintf("https://monzo.com/%03d/", i), LastMod: time.Date(2024, 1, 1+i, 0, 0, 0, 0, time.UTC)}
+	}
+	return urls
+}
+
+func TestFromResults(t *testing.T) {
+	lastModified := time.Date(2015, 10, 21, 7, 28, 0, 0, time.UTC)
+
+	urls := FromResults([]*crawler.PageResult{
+		{URL: "https://monzo.com/", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "text/html; charset=utf-8", LastModified: &lastModified},
+		{URL: "https://monzo.com/about/", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "text/html", Canonical: "https://monzo.com/about/"},
+		{URL: "https://monzo.com/about/?ref=home", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "text/html", Canonical: "https://monzo.com/about/"},
+		{URL: "https://monzo.com/private/", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "text/html", NoIndex: true},
+		{URL: "https://monzo.com/old-about/", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "text/html", FinalURL: "https://monzo.com/about/"},
+		{URL: "https://monzo.com/old-blog/", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "text/html", FinalURL: "https://monzo.com/blog/", Canonical: "https://monzo.com/blog/"},
+		{URL: "https://monzo.com/feed.xml", Status: crawler.PageSucceeded, StatusCode: 200, ContentType: "application/rss+xml"},
+		{URL: "https://monzo.com/help/", Status: crawler.PageFailed, StatusCode: 404},
+		{URL: "https://monzo.com/deep/", Status: crawler.PageSkippedDepth},
+		{URL: "https://twitter.com/monzo", Status: crawler.PageOutOfScope},
+	})
+
+	assert.Equal(t, []URL{
+		{Loc: "https://monzo.com/", LastMod: lastModified},
+		{Loc: "https://monzo.com/about/"},
+		{Loc: "https://monzo.com/blog/"},
+	}, urls)
+}
+
+func TestGenerator_Write(t *testing.T) {
+	t.Run("writes a single sitemap when the URLs fit", func(t *testing.T) {
+		dir := t.TempDir()
+		urls := append(testUrls(2), URL{Loc: "https://monzo.com/search?q=a&b"})
+
+		paths, err := NewGenerator().Write(dir, "https://monzo.com/", urls)
+		require.NoError(t, err)
+		assert.Equal(t, []string{filepath.Join(dir, "sitemap.xml")}, paths)
+
+		content := readFile(t, paths[0])
+		assert.Contains(t, string(content), `<?xml version="1.0" encoding="UTF-8"?>`)
+		assert.Contains(t, string(content), `<loc>https://monzo.com/search?q=a&amp;b</loc>`)
+
+		var set urlset
+		require.NoError(t, xml.Unmarshal(content, &set))
+		require.Len(t, set.URLs, 3)
+		assert.Equal(t, "https://monzo.com/000/", set.URLs[0].Loc)
+		assert.Equal(t, "2024-01-01T00:00:00Z", set.URLs[0].LastMod)
+		assert.Equal(t, "https://monzo.com/search?q=a&b", set.URLs[2].Loc)
+		assert.Empty(t, set.URLs[2].LastMod)
+	})
+
+	t.Run("writes an empty sitemap when there are no URLs", func(t *testing.T) {
+		paths, err := NewGenerator().Write(t.TempDir(), "https://monzo.com/", nil)
+		require.NoError(t, err)
+
+		var set urlset
+		require.NoError(t, xml.Unmarshal(readFile(t, paths[0]), &set))
+		assert.Empty(t, set.URLs)
+	})
+
+	t.Run("splits into an index and gzipped sitemaps past the URL limit", func(t *testing.T) {
+		dir := t.TempDir()
+
+		paths, err := NewGenerator(WithMaxUrls(2)).Write(dir, "https://monzo.com/sitemaps", testUrls(5))
+		require.NoError(t, err)
+		assert.Equal(t, []string{
+			filepath.Join(dir, "sitemap.xml"),
+			filepath.Join(dir, "sitemap-1.xml.gz"),
+			filepath.Join(dir, "sitemap-2.xml.gz"),
+			filepath.Join(dir, "sitemap-3.xml.gz"),
+		}, paths)
+
+		var index sitemapindex
+		require.NoError(t, xml.Unmarshal(readFile(t, paths[0]), &index))
+		require.Len(t, index.Sitemaps, 3)
+		assert.Equal(t, "https://monzo.com/sitemaps/sitemap-1.xml.gz", index.Sitemaps[0].Loc)
+		assert.Equal(t, "2024-01-02T00:00:00Z", index.Sitemaps[0].LastMod)
+
+		var locs []string
+		for _, path := range paths[1:] {
+			var set urlset
+			require.NoError(t, xml.Unmarshal(readFile(t, path), &set))
+			assert.LessOrEqual(t, len(set.URLs), 2)
+			for _, u := range set.URLs {
+				locs = append(locs, u.Loc)
+			}
+		}
+		assert.Len(t, locs, 5)
+		assert.Equal(t, "https://monzo.com/004/", locs[4])
+	})
+
+	t.Run("splits past the size limit", func(t *testing.T) {
+		dir := t.TempDir()
+		maxBytes := len(urlsetHeader) + len(urlsetFooter) + 3*len(entry(testUrls(1)[0]))
+
+		paths, err := NewGenerator(WithMaxBytes(maxBytes)).Write(dir, "https://monzo.com/", testUrls(7))
+		require.NoError(t, err)
+		require.Len(t, paths, 4)
+
+		for _, path := range paths[1:] {
+			content := readFile(t, path)
+			assert.LessOrEqual(t, len(content), maxBytes)
+		}
+	})
+}
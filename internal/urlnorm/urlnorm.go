@@ -0,0 +1,217 @@
+package urlnorm
+
+import (
+	"fmt"
+	"net/url"
+	"path"
+	"sort"
+	"strings"
+)
+
+// TrailingSlash is what the normalizer does with a trailing slash at the end of a URL's path.
+type TrailingSlash string
+
+const (
+	TrailingSlashKeep  TrailingSlash = "keep"  // Leave paths as they are, so that /a and /a/ are different pages.
+	TrailingSlashAdd   TrailingSlash = "add"   // Add a slash to paths that don't look like files, e.g. /a -> /a/ but not /a.pdf.
+	TrailingSlashStrip TrailingSlash = "strip" // Remove the slash from every path other than the root, e.g. /a/ -> /a.
+)
+
+// Normalizer rewrites URLs into a canonical form, so that different ways of writing the same page are taken as one page.
+type Normalizer struct {
+	stripParams   map[string]bool
+	stripPrefixes []string
+	trailingSlash TrailingSlash
+	sortQuery     bool
+}
+
+type Option func(n *Normalizer)
+
+// WithStripParams removes the given query parameters, e.g. tracking parameters such as "gclid", as well as ;name=value path parameters such as
+// ;jsessionid=. A trailing "*" matches any parameter that starts with what comes before it, e.g. "utm_*". Names are
+// matched case-insensitively.
+func WithStripParams(params ...string) Option {
+	return func(n *Normalizer) {
+		n.stripParams = make(map[string]bool)
+		n.stripPrefixes = nil
+		for _, p := range params {
+			p = strings.ToLower(p)
+			if prefix, ok := strings.CutSuffix(p, "*"); ok {
+				n.stripPrefixes = append(n.stripPrefixes, prefix)
+			} else {
+				n.stripParams[p] = true
+			}
+		}
+	}
+}
+
+// WithTrailingSlash sets what's done with trailing slashes. By default, they're kept as they are.
+func WithTrailingSlash(policy TrailingSlash) Option {
+	return func(n *Normalizer) {
+		n.trailingSlash = policy
+	}
+}
+
+// WithSortQuery sets whether query parameters are sorted by name. By default, they are.
+func WithSortQuery(sortQuery bool) Option {
+	return func(n *Normalizer) {
+		n.sortQuery = sortQuery
+	}
+}
+
+func NewNormalizer(opts ...Option) (*Normalizer, error) {
+	n := &Normalizer{
+		stripParams:   make(map[string]bool),
+		trailingSlash: TrailingSlashKeep,
+		sortQuery:     true,
+	}
+
+	for _, opt := range opts {
+		opt(n)
+	}
+
+	switch n.trailingSlash {
+	case TrailingSlashKeep, TrailingSlashAdd, TrailingSlashStrip:
+	default:
+		return nil, fmt.Errorf("unknown trailing slash policy %q", n.trailingSlash)
+	}
+
+	return n, nil
+}
+
+// Normalize returns the canonical form of an absolute URL:
+//   - the scheme and host are lowercased and the scheme's default port is removed,
+//   - "." and ".." segments are resolved and an empty path becomes "/",
+//   - the fragment is dropped,
+//   - tracking and session parameters are removed and the rest are sorted by name,
+//   - the trailing slash policy is applied.
+func (n *Normalizer) Normalize(rawUrl string) (string, error) {
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return "", err
+	}
+	if !u.IsAbs() {
+		return "", fmt.Errorf("not an absolute url: %s", rawUrl)
+	}
+
+	// Resolving an absolute URL against itself is how the url package removes dot segments from its path.
+	return n.normalize(u.ResolveReference(u))
+}
+
+func (n *Normalizer) normalize(u *url.URL) (string, error) {
+	if u.Opaque != "" {
+		return "", fmt.Errorf("not a hierarchical url: %s", u)
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if port := u.Port(); (u.Scheme == "http" && port == "80") || (u.Scheme == "https" && port == "443") {
+		u.Host = strings.TrimSuffix(u.Host, ":"+port)
+	}
+
+	u.Fragment, u.RawFragment = "", ""
+
+	p := n.stripPathParams(u.EscapedPath())
+	if p == "" {
+		p = "/"
+	}
+	p = n.applyTrailingSlash(p)
+	if err := setEscapedPath(u, p); err != nil {
+		return "", err
+	}
+
+	u.RawQuery = n.query(u.RawQuery)
+	u.ForceQuery = false
+
+	return u.String(), nil
+}
+
+// query removes the stripped parameters and any empty ones from a raw query, and sorts what's left by name. Parameters
+// keep their original encoding and, when they share a name, their relative order.
+func (n *Normalizer) query(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	params := make([]string, 0)
+	for _, p := range strings.Split(rawQuery, "&") {
+		if p == "" || n.stripped(paramName(p)) {
+			continue
+		}
+		params = append(params, p)
+	}
+
+	if n.sortQuery {
+		sort.SliceStable(params, func(i, j int) bool {
+			return paramName(params[i]) < paramName(params[j])
+		})
+	}
+
+	return strings.Join(params, "&")
+}
+
+// stripPathParams removes stripped ;name=value parameters from the end of each path segment, e.g. ;jsessionid=123.
+func (n *Normalizer) stripPathParams(escapedPath string) string {
+	if !strings.Contains(escapedPath, ";") {
+		return escapedPath
+	}
+
+	segments := strings.Split(escapedPath, "/")
+	for i, s := range segments {
+		parts := strings.Split(s, ";")
+		kept := parts[:1]
+		for _, p := range parts[1:] {
+			if !n.stripped(paramName(p)) {
+				kept = append(kept, p)
+			}
+		}
+		segments[i] = strings.Join(kept, ";")
+	}
+	return strings.Join(segments, "/")
+}
+
+func (n *Normalizer) stripped(name string) bool {
+	name = strings.ToLower(name)
+	if n.stripParams[name] {
+		return true
+	}
+	for _, prefix := range n.stripPrefixes {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (n *Normalizer) applyTrailingSlash(p string) string {
+	switch n.trailingSlash {
+	case TrailingSlashAdd:
+		if !strings.HasSuffix(p, "/") && !strings.Contains(path.Base(p), ".") {
+			return p + "/"
+		}
+	case TrailingSlashStrip:
+		if trimmed := strings.TrimRight(p, "/"); trimmed != "" {
+			return trimmed
+		}
+		return "/"
+	}
+	return p
+}
+
+// paramName returns the decoded name of a name=value parameter.
+func paramName(param string) string {
+	name, _, _ := strings.Cut(param, "=")
+	if unescaped, err := url.QueryUnescape(name); err == nil {
+		return unescaped
+	}
+	return name
+}
+
+func setEscapedPath(u *url.URL, escapedPath string) error {
+	p, err := url.PathUnescape(escapedPath)
+	if err != nil {
+		return err
+	}
+	u.Path, u.RawPath = p, escapedPath
+	return nil
+}
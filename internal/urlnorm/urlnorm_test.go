@@ -0,0 +1,178 @@
+package urlnorm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var stripParams = []string{"utm_*", "gclid", "jsessionid"}
+
+func TestNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []Option
+		url      string
+		expected string
+	}{
+		{
+			name:     "lowercases the scheme and host but not the path",
+			url:      "HTTPS://Monzo.COM/Help/",
+			expected: "https://monzo.com/Help/",
+		},
+		{
+			name:     "removes the scheme's default port",
+			url:      "https://monzo.com:443/a",
+			expected: "https://monzo.com/a",
+		},
+		{
+			name:     "removes the default http port",
+			url:      "http://monzo.com:80/a",
+			expected: "http://monzo.com/a",
+		},
+		{
+			name:     "keeps other ports",
+			url:      "http://monzo.com:443/a",
+			expected: "http://monzo.com:443/a",
+		},
+		{
+			name:     "adds a root path",
+			url:      "https://monzo.com",
+			expected: "https://monzo.com/",
+		},
+		{
+			name:     "resolves dot segments",
+			url:      "https://monzo.com/a/./b/../c",
+			expected: "https://monzo.com/a/c",
+		},
+		{
+			name:     "drops the fragment",
+			url:      "https://monzo.com/a#top",
+			expected: "https://monzo.com/a",
+		},
+		{
+			name:     "sorts query parameters by name, keeping the order of repeated ones",
+			url:      "https://monzo.com/a?b=2&a=2&c=1&a=1",
+			expected: "https://monzo.com/a?a=2&a=1&b=2&c=1",
+		},
+		{
+			name:     "keeps the query's encoding",
+			url:      "https://monzo.com/search?q=a%20b&page=2",
+			expected: "https://monzo.com/search?page=2&q=a%20b",
+		},
+		{
+			name:     "drops empty query parameters and an empty query",
+			url:      "https://monzo.com/a?&&",
+			expected: "https://monzo.com/a",
+		},
+		{
+			name:     "keeps the query order when sorting is turned off",
+			opts:     []Option{WithSortQuery(false)},
+			url:      "https://monzo.com/a?b=2&a=1",
+			expected: "https://monzo.com/a?b=2&a=1",
+		},
+		{
+			name:     "strips listed parameters, case-insensitively and by prefix",
+			opts:     []Option{WithStripParams(stripParams...)},
+			url:      "https://monzo.com/A?UTM_source=x&utm_medium=y&gclid=1&page=2",
+			expected: "https://monzo.com/A?page=2",
+		},
+		{
+			name:     "strips listed path parameters",
+			opts:     []Option{WithStripParams(stripParams...)},
+			url:      "https://monzo.com/a;jsessionid=123/b;v=1",
+			expected: "https://monzo.com/a/b;v=1",
+		},
+		{
+			name:     "keeps parameters when none are listed",
+			url:      "https://monzo.com/a?utm_source=x",
+			expected: "https://monzo.com/a?utm_source=x",
+		},
+		{
+			name:     "keeps trailing slashes by default",
+			url:      "https://monzo.com/a",
+			expected: "https://monzo.com/a",
+		},
+		{
+			name:     "adds trailing slashes to paths that don't look like files",
+			opts:     []Option{WithTrailingSlash(TrailingSlashAdd)},
+			url:      "https://monzo.com/a?b=1",
+			expected: "https://monzo.com/a/?b=1",
+		},
+		{
+			name:     "doesn't add trailing slashes to files",
+			opts:     []Option{WithTrailingSlash(TrailingSlashAdd)},
+			url:      "https://monzo.com/a.pdf",
+			expected: "https://monzo.com/a.pdf",
+		},
+		{
+			name:     "strips trailing slashes",
+			opts:     []Option{WithTrailingSlash(TrailingSlashStrip)},
+			url:      "https://monzo.com/a/",
+			expected: "https://monzo.com/a",
+		},
+		{
+			name:     "doesn't strip the root path",
+			opts:     []Option{WithTrailingSlash(TrailingSlashStrip)},
+			url:      "https://monzo.com/",
+			expected: "https://monzo.com/",
+		},
+		{
+			name:     "keeps escaped characters in the path",
+			url:      "https://monzo.com/a%2Fb/c%20d",
+			expected: "https://monzo.com/a%2Fb/c%20d",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, err := NewNormalizer(tt.opts...)
+			require.NoError(t, err)
+
+			actual, err := n.Normalize(tt.url)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, actual)
+		})
+	}
+
+	t.Run("takes every variant of a page as the same page", func(t *testing.T) {
+		n, err := NewNormalizer(WithStripParams(stripParams...), WithTrailingSlash(TrailingSlashStrip))
+		require.NoError(t, err)
+
+		for _, u := range []string{
+			"https://monzo.com/a",
+			"https://monzo.com/a/",
+			"https://monzo.com/a#top",
+			"https://monzo.com/b/../a?utm_source=x",
+			"HTTPS://Monzo.com:443/a",
+		} {
+			actual, err := n.Normalize(u)
+			require.NoError(t, err)
+			assert.Equal(t, "https://monzo.com/a", actual, u)
+		}
+	})
+
+	t.Run("returns an error for relative urls", func(t *testing.T) {
+		n, err := NewNormalizer()
+		require.NoError(t, err)
+
+		_, err = n.Normalize("/a")
+		assert.Error(t, err)
+	})
+
+	t.Run("returns an error for opaque urls", func(t *testing.T) {
+		n, err := NewNormalizer()
+		require.NoError(t, err)
+
+		_, err = n.Normalize("mailto:someone@monzo.com")
+		assert.Error(t, err)
+	})
+}
+
+func TestNewNormalizer(t *testing.T) {
+	t.Run("returns an error for an unknown trailing slash policy", func(t *testing.T) {
+		_, err := NewNormalizer(WithTrailingSlash("sometimes"))
+		assert.EqualError(t, err, `unknown trailing slash policy "sometimes"`)
+	})
+}